@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+// Package bing implements search.Client against the Bing Image Search
+// v7 API.
+package bing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/discursive-image/dic/search"
+)
+
+// EnvKey is the environment variable the "bing" backend registered with
+// search.Register reads its key from. NewSC itself only uses the key it
+// is given.
+const EnvKey = "BING_SEARCH_KEY"
+
+func init() {
+	search.Register("bing", func(ctx context.Context) (search.Client, error) {
+		return NewSC(os.Getenv(EnvKey)), nil
+	})
+}
+
+const baseURL = "https://api.bing.microsoft.com/v7.0/images/search"
+
+// SC is a Bing search client, implementing search.Client. Initialize it
+// using NewSC.
+type SC struct {
+	// Key authenticates against the Bing Search API.
+	// https://docs.microsoft.com/en-us/bing/search-apis/bing-image-search/overview
+	Key string
+}
+
+var _ search.Client = (*SC)(nil)
+
+// NewSC returns a new Bing search client.
+func NewSC(k string) *SC {
+	return &SC{Key: k}
+}
+
+func (c *SC) Validate() error {
+	if c.Key == "" {
+		return fmt.Errorf("search client key missing")
+	}
+	return nil
+}
+
+type isr struct {
+	ContentURL     string `json:"contentUrl"`
+	ThumbnailURL   string `json:"thumbnailUrl"`
+	EncodingFormat string `json:"encodingFormat"`
+	HostPageURL    string `json:"hostPageUrl"`
+}
+
+func (i *isr) result() *search.Result {
+	return &search.Result{
+		Link:        i.ContentURL,
+		Thumb:       i.ThumbnailURL,
+		Mime:        "image/" + i.EncodingFormat,
+		ContextLink: i.HostPageURL,
+	}
+}
+
+func decodeISR(r io.Reader) ([]*isr, error) {
+	var res struct {
+		Value []*isr `json:"value"`
+	}
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return nil, fmt.Errorf("unable to decode response: %w", err)
+	}
+	return res.Value, nil
+}
+
+// imageType maps the backend-agnostic ImgType values onto the ones the
+// Bing API expects; unrecognized values are left unset.
+func imageType(s string) string {
+	switch s {
+	case "clipart":
+		return "Clipart"
+	case "line", "lineart":
+		return "Line"
+	case "photo":
+		return "Photo"
+	case "animatedgif":
+		return "AnimatedGif"
+	case "transparent":
+		return "Transparent"
+	default:
+		return ""
+	}
+}
+
+// imageSize maps the backend-agnostic ImgSize values onto the ones the
+// Bing API expects; unrecognized values are left unset.
+func imageSize(s string) string {
+	switch s {
+	case "small", "icon":
+		return "Small"
+	case "medium":
+		return "Medium"
+	case "large", "huge", "xlarge", "xxlarge":
+		return "Large"
+	default:
+		return ""
+	}
+}
+
+var client = &http.Client{}
+
+// SearchImages searches Bing for images.
+func (c *SC) SearchImages(ctx context.Context, q string, opts ...search.Option) ([]*search.Result, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	var f search.Filter
+	for _, o := range opts {
+		o(&f)
+	}
+
+	v := url.Values{}
+	v.Set("q", q)
+	if t := imageType(f.ImgType); t != "" {
+		v.Set("imageType", t)
+	}
+	if s := imageSize(f.ImgSize); s != "" {
+		v.Set("size", s)
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse base url: %w", err)
+	}
+	u.RawQuery = v.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build bing search request: %w", err)
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", c.Key)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to contact bing search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var res struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			return nil, fmt.Errorf("bing search returned %s", resp.Status)
+		}
+		return nil, fmt.Errorf(res.Message)
+	}
+
+	items, err := decodeISR(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*search.Result, len(items))
+	for i, item := range items {
+		results[i] = item.result()
+	}
+	return results, nil
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+package bing
+
+import (
+	"strings"
+	"testing"
+)
+
+var bingResponse = `{
+    "_type": "Images",
+    "instrumentation": {
+        "pageLoadPingUrl": "https://www.bingapis.com/api/ping/pageload"
+    },
+    "readLink": "https://api.bing.microsoft.com/v7.0/images/search?q=cats",
+    "value": [
+        {
+            "contentUrl": "https://example.com/cat.jpg",
+            "contentSize": "123456 B",
+            "encodingFormat": "jpeg",
+            "hostPageUrl": "https://example.com/cats",
+            "name": "A cat",
+            "thumbnailUrl": "https://example.com/cat-thumb.jpg",
+            "webSearchUrl": "https://www.bing.com/images/search?q=cats"
+        }
+    ]
+}
+`
+
+func TestDecodeISR(t *testing.T) {
+	items, err := decodeISR(strings.NewReader(bingResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("unexpected items count: %d", len(items))
+	}
+
+	got := items[0].result()
+	if got.Link != "https://example.com/cat.jpg" {
+		t.Errorf("Link = %q, want https://example.com/cat.jpg", got.Link)
+	}
+	if got.Thumb != "https://example.com/cat-thumb.jpg" {
+		t.Errorf("Thumb = %q, want https://example.com/cat-thumb.jpg", got.Thumb)
+	}
+	if got.Mime != "image/jpeg" {
+		t.Errorf("Mime = %q, want image/jpeg", got.Mime)
+	}
+	if got.ContextLink != "https://example.com/cats" {
+		t.Errorf("ContextLink = %q, want https://example.com/cats", got.ContextLink)
+	}
+}
+
+func TestImageType(t *testing.T) {
+	cases := map[string]string{
+		"clipart":     "Clipart",
+		"line":        "Line",
+		"lineart":     "Line",
+		"photo":       "Photo",
+		"animatedgif": "AnimatedGif",
+		"transparent": "Transparent",
+		"unknown":     "",
+	}
+	for in, want := range cases {
+		if got := imageType(in); got != want {
+			t.Errorf("imageType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	cases := map[string]string{
+		"small":   "Small",
+		"icon":    "Small",
+		"medium":  "Medium",
+		"large":   "Large",
+		"huge":    "Large",
+		"xlarge":  "Large",
+		"xxlarge": "Large",
+		"unknown": "",
+	}
+	for in, want := range cases {
+		if got := imageSize(in); got != want {
+			t.Errorf("imageSize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
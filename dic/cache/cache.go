@@ -0,0 +1,336 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+// Package cache implements a ref-counted, persisted cache of search
+// results, inspired by buildkit's cache manager: a Manager hands out
+// Refs that keep an entry alive while held, entries are written to an
+// on-disk JSON directory so concurrent and later dic runs can share
+// validated image links, and Evict reclaims entries past MaxEntries,
+// TTL or MaxDiskBytes.
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/discursive-image/dic/search"
+)
+
+// Config bounds a Manager's memory and disk footprint.
+type Config struct {
+	// Dir is the on-disk directory entries are persisted under, one
+	// JSON file per key. Left empty, the Manager keeps entries in
+	// memory only. Created if it does not exist.
+	Dir string
+	// MaxEntries caps how many keys the Manager keeps at once; once
+	// exceeded, the least recently used unreferenced entry is evicted.
+	MaxEntries int
+	// TTL is how long an entry is kept after it was last used, before
+	// Evict removes it outright.
+	TTL time.Duration
+	// MaxDiskBytes caps the total size of Dir; Evict removes entries,
+	// least recently used first, to stay under it. Ignored when Dir is
+	// empty.
+	MaxDiskBytes int64
+	// FreshnessWindow is how long a validated image link is trusted
+	// before Ref.Next re-checks it with an HTTP HEAD request.
+	FreshnessWindow time.Duration
+}
+
+func (c Config) freshnessWindow() time.Duration {
+	if c.FreshnessWindow > 0 {
+		return c.FreshnessWindow
+	}
+	return time.Hour
+}
+
+// image is one candidate result of a cached key, together with its
+// validation state.
+type image struct {
+	Result    *search.Result `json:"result"`
+	Checked   bool           `json:"checked"`
+	CheckedAt time.Time      `json:"checkedAt"`
+	Valid     bool           `json:"valid"`
+}
+
+// entry is everything the Manager keeps for a single key. Its fields are
+// guarded by the owning Manager's mu, not a lock of their own: Get/Put/
+// Evict and Ref.Next all mutate LastUsed/Index and must agree on one lock.
+type entry struct {
+	Key      string    `json:"key"`
+	Images   []*image  `json:"images"`
+	Index    int       `json:"index"`
+	LastUsed time.Time `json:"lastUsed"`
+
+	refs int
+}
+
+// Ref is a held reference into a cache entry. Holding one keeps the
+// entry from being evicted; call Release once done with it.
+type Ref struct {
+	m *Manager
+	e *entry
+}
+
+// Release drops this Ref's hold on the entry, making it eligible for
+// eviction again.
+func (r *Ref) Release() {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	r.e.refs--
+}
+
+// discardFunc decides whether link should be treated as unusable. It is
+// a var so tests can stub it out instead of making real HTTP requests.
+var discardFunc = defaultDiscard
+
+// Next returns the next valid image in the ring, lazily HEAD-checking
+// (or re-checking, once older than the Manager's FreshnessWindow) each
+// candidate. Unlike the modulo walk it replaces, it always inspects
+// each image at most once per call, so it terminates for any slice
+// length, including 1, and never panics on one.
+func (r *Ref) Next() *search.Result {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	e := r.e
+	n := len(e.Images)
+	if n == 0 {
+		return nil
+	}
+
+	fresh := r.m.cfg.freshnessWindow()
+	for i := 0; i < n; i++ {
+		idx := (e.Index + i) % n
+		img := e.Images[idx]
+		if !img.Checked || time.Since(img.CheckedAt) > fresh {
+			img.Valid = !discardFunc(img.Result.Link)
+			img.Checked = true
+			img.CheckedAt = time.Now()
+		}
+		if img.Valid {
+			e.Index = (idx + 1) % n
+			e.LastUsed = time.Now()
+			r.m.persist(e)
+			return img.Result
+		}
+	}
+	return nil
+}
+
+// Manager is a ref-counted cache of search.Result slices, keyed by the
+// query that produced them.
+type Manager struct {
+	cfg Config
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewManager returns a Manager bound by cfg, loading any entries
+// persisted under cfg.Dir from a previous run.
+func NewManager(cfg Config) (*Manager, error) {
+	m := &Manager{cfg: cfg, entries: map[string]*entry{}}
+	if cfg.Dir == "" {
+		return m, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to prepare cache dir: %w", err)
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Manager) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(m.cfg.Dir, fmt.Sprintf("%x.json", sum))
+}
+
+func (m *Manager) load() error {
+	infos, err := ioutil.ReadDir(m.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("unable to list cache dir: %w", err)
+	}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(m.cfg.Dir, info.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to read cache entry %s: %w", info.Name(), err)
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("unable to decode cache entry %s: %w", info.Name(), err)
+		}
+		m.entries[e.Key] = &e
+	}
+	return nil
+}
+
+// persist writes e to disk, best effort: a failure here only costs the
+// next run a cache hit, so it isn't surfaced as an error.
+func (m *Manager) persist(e *entry) {
+	if m.cfg.Dir == "" {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	ioutil.WriteFile(m.path(e.Key), data, 0644)
+}
+
+func (m *Manager) remove(e *entry) {
+	delete(m.entries, e.Key)
+	if m.cfg.Dir != "" {
+		os.Remove(m.path(e.Key))
+	}
+}
+
+// Get returns a Ref to the entry cached under key, if any.
+func (m *Manager) Get(key string) (*Ref, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e.refs++
+	e.LastUsed = time.Now()
+	return &Ref{m: m, e: e}, true
+}
+
+// Put caches results under key, replacing any entry already there, and
+// returns a Ref to it. Evict runs afterwards to enforce the Manager's
+// bounds.
+func (m *Manager) Put(key string, results []*search.Result) *Ref {
+	m.mu.Lock()
+
+	images := make([]*image, len(results))
+	for i, r := range results {
+		images[i] = &image{Result: r}
+	}
+	e := &entry{
+		Key:      key,
+		Images:   images,
+		LastUsed: time.Now(),
+		refs:     1,
+	}
+	m.entries[key] = e
+	m.persist(e)
+	m.mu.Unlock()
+
+	m.Evict()
+	return &Ref{m: m, e: e}
+}
+
+// Evict reclaims entries past the Manager's MaxEntries, TTL or
+// MaxDiskBytes bounds. Entries with an outstanding Ref are never
+// evicted. It is safe to call concurrently, and dic calls it after
+// every Put; callers embedding Manager in a longer-running process can
+// also call it on a timer.
+func (m *Manager) Evict() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lru := make([]*entry, 0, len(m.entries))
+	for _, e := range m.entries {
+		lru = append(lru, e)
+	}
+	sort.Slice(lru, func(i, j int) bool { return lru[i].LastUsed.Before(lru[j].LastUsed) })
+
+	if m.cfg.TTL > 0 {
+		now := time.Now()
+		for _, e := range lru {
+			if e.refs == 0 && now.Sub(e.LastUsed) > m.cfg.TTL {
+				m.remove(e)
+			}
+		}
+	}
+
+	if m.cfg.MaxEntries > 0 {
+		live := m.liveLRULocked(lru)
+		for _, e := range live {
+			if len(m.entries) <= m.cfg.MaxEntries {
+				break
+			}
+			if e.refs == 0 {
+				m.remove(e)
+			}
+		}
+	}
+
+	if m.cfg.Dir != "" && m.cfg.MaxDiskBytes > 0 {
+		return m.evictToDiskBudgetLocked(m.liveLRULocked(lru))
+	}
+	return nil
+}
+
+// liveLRULocked filters lru down to the entries still present in
+// m.entries (some of lru may have been removed by an earlier pass),
+// oldest first.
+func (m *Manager) liveLRULocked(lru []*entry) []*entry {
+	live := make([]*entry, 0, len(lru))
+	for _, e := range lru {
+		if _, ok := m.entries[e.Key]; ok {
+			live = append(live, e)
+		}
+	}
+	return live
+}
+
+func (m *Manager) evictToDiskBudgetLocked(lru []*entry) error {
+	var total int64
+	infos, err := ioutil.ReadDir(m.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("unable to list cache dir: %w", err)
+	}
+	for _, info := range infos {
+		total += info.Size()
+	}
+
+	for _, e := range lru {
+		if total <= m.cfg.MaxDiskBytes || e.refs != 0 {
+			continue
+		}
+		info, err := os.Stat(m.path(e.Key))
+		if err == nil {
+			total -= info.Size()
+		}
+		m.remove(e)
+	}
+	return nil
+}
+
+var fastClient = &http.Client{Timeout: 2 * time.Second}
+
+// defaultDiscard reports whether link should be treated as unusable: it
+// is discarded if it can't be HEAD-ed, returns a 4xx/5xx status, or
+// doesn't advertise an image content-type.
+func defaultDiscard(link string) bool {
+	resp, err := fastClient.Head(link)
+	if err != nil {
+		return true
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return true
+	}
+	t := resp.Header.Get("content-type")
+	return !strings.Contains(t, "image")
+}
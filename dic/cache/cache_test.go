@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/discursive-image/dic/search"
+)
+
+// stubDiscard replaces discardFunc for the duration of a test, restoring it
+// on cleanup so other tests keep seeing the real HTTP-based default.
+func stubDiscard(t *testing.T, bad map[string]bool) {
+	t.Helper()
+	old := discardFunc
+	discardFunc = func(link string) bool { return bad[link] }
+	t.Cleanup(func() { discardFunc = old })
+}
+
+// TestRefNextSingleImage is the regression test for the ring-walk panic:
+// Next used to index past a length-1 slice on its second call. It must
+// terminate and keep returning the same image instead.
+func TestRefNextSingleImage(t *testing.T) {
+	stubDiscard(t, nil)
+
+	m, err := NewManager(Config{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	ref := m.Put("key", []*search.Result{{Link: "https://example.com/a.jpg"}})
+
+	for i := 0; i < 3; i++ {
+		got := ref.Next()
+		if got == nil || got.Link != "https://example.com/a.jpg" {
+			t.Fatalf("Next() call %d = %v, want a.jpg", i, got)
+		}
+	}
+}
+
+func TestRefNextSkipsInvalid(t *testing.T) {
+	stubDiscard(t, map[string]bool{"https://example.com/bad.jpg": true})
+
+	m, err := NewManager(Config{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	ref := m.Put("key", []*search.Result{
+		{Link: "https://example.com/bad.jpg"},
+		{Link: "https://example.com/good.jpg"},
+	})
+
+	got := ref.Next()
+	if got == nil || got.Link != "https://example.com/good.jpg" {
+		t.Fatalf("Next() = %v, want good.jpg", got)
+	}
+}
+
+func TestRefNextAllInvalid(t *testing.T) {
+	stubDiscard(t, map[string]bool{
+		"https://example.com/a.jpg": true,
+		"https://example.com/b.jpg": true,
+	})
+
+	m, err := NewManager(Config{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	ref := m.Put("key", []*search.Result{
+		{Link: "https://example.com/a.jpg"},
+		{Link: "https://example.com/b.jpg"},
+	})
+
+	if got := ref.Next(); got != nil {
+		t.Fatalf("Next() = %v, want nil", got)
+	}
+}
+
+func TestRefNextAdvancesRing(t *testing.T) {
+	stubDiscard(t, nil)
+
+	m, err := NewManager(Config{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	ref := m.Put("key", []*search.Result{
+		{Link: "https://example.com/a.jpg"},
+		{Link: "https://example.com/b.jpg"},
+		{Link: "https://example.com/c.jpg"},
+	})
+
+	want := []string{
+		"https://example.com/a.jpg",
+		"https://example.com/b.jpg",
+		"https://example.com/c.jpg",
+		"https://example.com/a.jpg",
+	}
+	for i, w := range want {
+		got := ref.Next()
+		if got == nil || got.Link != w {
+			t.Fatalf("Next() call %d = %v, want %s", i, got, w)
+		}
+	}
+}
+
+func TestGetReturnsPutEntry(t *testing.T) {
+	m, err := NewManager(Config{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	put := m.Put("key", []*search.Result{{Link: "https://example.com/a.jpg"}})
+	put.Release()
+
+	got, ok := m.Get("key")
+	if !ok {
+		t.Fatal("Get() = false, want true")
+	}
+	got.Release()
+
+	if _, ok := m.Get("missing"); ok {
+		t.Fatal("Get(\"missing\") = true, want false")
+	}
+}
+
+func TestEvictMaxEntries(t *testing.T) {
+	m, err := NewManager(Config{MaxEntries: 1})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	older := m.Put("older", []*search.Result{{Link: "https://example.com/a.jpg"}})
+	older.Release()
+	time.Sleep(2 * time.Millisecond)
+
+	newer := m.Put("newer", []*search.Result{{Link: "https://example.com/b.jpg"}})
+	newer.Release()
+
+	if _, ok := m.Get("older"); ok {
+		t.Fatal("older entry should have been evicted to respect MaxEntries")
+	}
+	if _, ok := m.Get("newer"); !ok {
+		t.Fatal("newer entry should still be cached")
+	}
+}
+
+func TestEvictKeepsReferencedEntries(t *testing.T) {
+	m, err := NewManager(Config{MaxEntries: 1})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	held := m.Put("held", []*search.Result{{Link: "https://example.com/a.jpg"}})
+	time.Sleep(2 * time.Millisecond)
+	m.Put("other", []*search.Result{{Link: "https://example.com/b.jpg"}}).Release()
+
+	if _, ok := m.Get("held"); !ok {
+		t.Fatal("entry with an outstanding Ref should not be evicted")
+	}
+	held.Release()
+}
+
+func TestEvictTTL(t *testing.T) {
+	m, err := NewManager(Config{TTL: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	m.Put("key", []*search.Result{{Link: "https://example.com/a.jpg"}}).Release()
+	time.Sleep(5 * time.Millisecond)
+
+	if err := m.Evict(); err != nil {
+		t.Fatalf("Evict: %v", err)
+	}
+	if _, ok := m.Get("key"); ok {
+		t.Fatal("entry past its TTL should have been evicted")
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+// Command dicd exposes a search.Client backend over gRPC, so that dic's
+// CLI (or any other client generated from rpc/search.proto) can resolve
+// many queries over one long-lived connection instead of spawning a
+// process per CSV file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/discursive-image/dic/bing"
+	"github.com/discursive-image/dic/cache"
+	_ "github.com/discursive-image/dic/duckduckgo"
+	"github.com/discursive-image/dic/google"
+	"github.com/discursive-image/dic/rpc"
+	"github.com/discursive-image/dic/rpc/rpcpb"
+	"github.com/discursive-image/dic/search"
+	"github.com/discursive-image/dic/static"
+	"google.golang.org/grpc"
+)
+
+func errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, os.Args[0]+" error: "+format+"\n", args...)
+}
+
+func exitf(format string, args ...interface{}) {
+	errorf(format, args...)
+	os.Exit(1)
+}
+
+func main() {
+	addr := flag.String("addr", ":8444", "Address to serve the SearchService gRPC API on.")
+	e := flag.String("e", "google", "Image search engine to use. Choose google, bing, duckduckgo or static.")
+	k := flag.String("k", os.Getenv(google.EnvKey), "Google API key. Used only if engine is google.")
+	cx := flag.String("cx", os.Getenv(google.EnvCx), "Google custom search engine ID. Used only if engine is google.")
+	bk := flag.String("bk", os.Getenv(bing.EnvKey), "Bing API key. Used only if engine is bing.")
+	manifest := flag.String("manifest", os.Getenv(static.EnvManifest), "Path to a JSON manifest mapping queries to results. Used only if engine is static.")
+	cacheDir := flag.String("cache-dir", "", "Directory to persist validated search results under, shared across runs. Left empty, the cache is kept in memory only.")
+	cacheEntries := flag.Int("cache-max-entries", 10000, "Maximum number of distinct queries the cache keeps at once.")
+	cacheTTL := flag.Duration("cache-ttl", 7*24*time.Hour, "How long a cached query is kept since it was last used.")
+	cacheMaxBytes := flag.Int64("cache-max-bytes", 256<<20, "Maximum size, in bytes, of -cache-dir.")
+	cacheFreshness := flag.Duration("cache-freshness", time.Hour, "How long a validated image link is trusted before being re-checked.")
+	flag.Parse()
+
+	os.Setenv(google.EnvKey, *k)
+	os.Setenv(google.EnvCx, *cx)
+	os.Setenv(bing.EnvKey, *bk)
+	os.Setenv(static.EnvManifest, *manifest)
+
+	sc, err := search.New(context.Background(), *e)
+	if err != nil {
+		exitf("unable to initiate search engine: %v", err)
+	}
+
+	cm, err := cache.NewManager(cache.Config{
+		Dir:             *cacheDir,
+		MaxEntries:      *cacheEntries,
+		TTL:             *cacheTTL,
+		MaxDiskBytes:    *cacheMaxBytes,
+		FreshnessWindow: *cacheFreshness,
+	})
+	if err != nil {
+		exitf("unable to initiate result cache: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		exitf("unable to listen on %s: %v", *addr, err)
+	}
+
+	srv := grpc.NewServer()
+	rpcpb.RegisterSearchServiceServer(srv, rpc.NewService(sc, cm))
+
+	fmt.Fprintf(os.Stderr, "%s: serving SearchService on %s\n", os.Args[0], *addr)
+	if err := srv.Serve(lis); err != nil {
+		exitf("serve exited: %v", err)
+	}
+}
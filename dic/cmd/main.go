@@ -11,15 +11,16 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
-	"strings"
-	"sync"
 	"time"
 
+	"github.com/discursive-image/dic/bing"
+	"github.com/discursive-image/dic/cache"
+	_ "github.com/discursive-image/dic/duckduckgo"
 	"github.com/discursive-image/dic/google"
+	"github.com/discursive-image/dic/search"
+	"github.com/discursive-image/dic/static"
 )
 
 func logf(format string, args ...interface{}) {
@@ -35,17 +36,27 @@ func exitf(format string, args ...interface{}) {
 	os.Exit(1)
 }
 
-func handleQSearch(ctx context.Context, gsc *google.SC, q string, opts ...func(url.Values)) {
-	items, err := gsc.SearchImages(ctx, q, opts...)
-	if err != nil {
-		exitf(err.Error())
+func handleQSearch(ctx context.Context, sc search.Client, cm *cache.Manager, q string, opts ...search.Option) {
+	ref, ok := cm.Get(q)
+	if !ok {
+		items, err := sc.SearchImages(ctx, q, opts...)
+		if err != nil {
+			exitf(err.Error())
+		}
+		if len(items) == 0 {
+			fmt.Printf("no results\n")
+			return
+		}
+		ref = cm.Put(q, items)
 	}
-	switch {
-	case len(items) == 0:
-		fmt.Printf("no results\n")
-	default:
-		fmt.Println(items[0].Link)
+	defer ref.Release()
+
+	image := ref.Next()
+	if image == nil {
+		fmt.Printf("no valid image found\n")
+		return
 	}
+	fmt.Println(image.Link)
 }
 
 func openInputFile(in string) (io.ReadCloser, error) {
@@ -62,115 +73,14 @@ func openInputFile(in string) (io.ReadCloser, error) {
 
 const maxcc int = 10
 
-type touchedImage struct {
-	image   *google.ISR
-	checked bool
-	valid   bool
-}
-
-type imageRing struct {
-	all   []*touchedImage
-	index int
-}
-
-var fastClient = &http.Client{
-	Timeout: 2 * time.Second,
-}
-
-func discard(link string) bool {
-	resp, err := fastClient.Head(link)
-	if err != nil {
-		return true
-	}
-	resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return true
-	}
-	t := resp.Header.Get("content-type")
-	return !strings.Contains(t, "image")
-}
-
-func (ir *imageRing) next() *google.ISR {
-	if len(ir.all) == 0 {
-		return nil
-	}
-
-	// Lazily check images before returning them.
-
-	var ti *touchedImage
-	var found bool
-	var index int
-	for i := ir.index; i < len(ir.all); i = (i + 1) % (len(ir.all) - 1) {
-		ti = ir.all[i]
-		if !ti.checked {
-			ti.valid = !discard(ti.image.Link)
-		}
-		if ti.valid {
-			found = true
-			index = i
-			break
-		}
-	}
-	if !found {
-		return nil
-	}
-	ir.index = (index + 1) % (len(ir.all) - 1)
-	return ti.image
-}
-
-type ringCache struct {
-	sync.Mutex
-	m map[string]*imageRing
-}
-
-func newRingCache() *ringCache {
-	return &ringCache{
-		m: make(map[string]*imageRing),
-	}
-}
-
-func (c *ringCache) next(k string) (*google.ISR, bool) {
-	c.Lock()
-	defer c.Unlock()
-
-	ring, ok := c.m[k]
-	if !ok {
-		return nil, false
-	}
-	image := ring.next()
-	if image == nil {
-		// something is broken with this ring, delete it.
-		delete(c.m, k)
-		return nil, false
-	}
-	return image, true
-}
-
-func (c *ringCache) set(k string, results []*google.ISR) {
-	c.Lock()
-	defer c.Unlock()
-
-	all := make([]*touchedImage, len(results))
-	for i, v := range results {
-		all[i] = &touchedImage{
-			image: v,
-		}
-	}
-	c.m[k] = &imageRing{
-		all:   all,
-		index: 0,
-	}
-}
-
 type ImageRequest struct {
-	gsc   *google.SC
+	sc    search.Client
 	c     int
 	rec   []string
-	opts  []func(url.Values)
+	opts  []search.Option
 	done  chan bool
 	err   error
-	cache *ringCache
+	cache *cache.Manager
 }
 
 func (r *ImageRequest) Run(ctx context.Context) {
@@ -182,29 +92,27 @@ func (r *ImageRequest) Run(ctx context.Context) {
 
 	k := r.rec[r.c]
 
-	// Check if the cache contains the value.
-	image, ok := r.cache.next(k)
-	if ok {
-		r.rec = append(r.rec, image.Link)
-		return
-	}
-
-	// If not, search for the image.
-	items, err := r.gsc.SearchImages(ctx, k, r.opts...)
-	if err != nil {
-		r.err = err
-		return
-	}
-	if len(items) == 0 {
-		r.err = fmt.Errorf("no results")
-		r.rec = append(r.rec, "")
-		return
+	// Check if the cache already holds results for k.
+	ref, ok := r.cache.Get(k)
+	if !ok {
+		// If not, search for the image and cache whatever came back.
+		items, err := r.sc.SearchImages(ctx, k, r.opts...)
+		if err != nil {
+			r.err = err
+			return
+		}
+		if len(items) == 0 {
+			r.err = fmt.Errorf("no results")
+			r.rec = append(r.rec, "")
+			return
+		}
+		ref = r.cache.Put(k, items)
 	}
-	r.cache.set(k, items)
+	defer ref.Release()
 
-	image, ok = r.cache.next(k)
-	if !ok {
-		r.err = fmt.Errorf("cache inconsistency")
+	image := ref.Next()
+	if image == nil {
+		r.err = fmt.Errorf("no valid image found for %q", k)
 		r.rec = append(r.rec, "")
 		return
 	}
@@ -234,7 +142,7 @@ func enqueueImageRequest(rx chan *ImageRequest, errc chan<- error) {
 	}
 }
 
-func handleSSearch(ctx context.Context, gsc *google.SC, in string, c int, opts ...func(url.Values)) {
+func handleSSearch(ctx context.Context, sc search.Client, cm *cache.Manager, in string, c int, opts ...search.Option) {
 	r, err := openInputFile(in)
 	if err != nil {
 		exitf(err.Error())
@@ -245,7 +153,6 @@ func handleSSearch(ctx context.Context, gsc *google.SC, in string, c int, opts .
 	sem := make(chan struct{}, maxcc) // concurrency semaphore.
 	errc := make(chan error)          // error channel, used for error reporting from writer.
 	tx := make(chan *ImageRequest)    // wrapped records transmitter.
-	cache := newRingCache()
 	defer close(tx)
 
 	go enqueueImageRequest(tx, errc)
@@ -280,9 +187,9 @@ func handleSSearch(ctx context.Context, gsc *google.SC, in string, c int, opts .
 		rw := &ImageRequest{
 			c:     c,
 			rec:   rec,
-			gsc:   gsc,
+			sc:    sc,
 			done:  make(chan bool),
-			cache: cache,
+			cache: cm,
 		}
 
 		tx <- rw // send item though channel to preserve ordering.
@@ -302,19 +209,22 @@ func handleSSearch(ctx context.Context, gsc *google.SC, in string, c int, opts .
 	}
 }
 
-const (
-	envGoogleKey = "GOOGLE_SEARCH_KEY"
-	envGoogleCx  = "GOOGLE_SEARCH_CX"
-)
-
 func main() {
-	k := flag.String("k", os.Getenv(envGoogleKey), "Google API key.")
-	cx := flag.String("cx", os.Getenv(envGoogleCx), "Google custom search engine ID.")
+	e := flag.String("e", "google", "Image search engine to use. Choose google, bing, duckduckgo or static.")
+	k := flag.String("k", os.Getenv(google.EnvKey), "Google API key. Used only if engine is google.")
+	cx := flag.String("cx", os.Getenv(google.EnvCx), "Google custom search engine ID. Used only if engine is google.")
+	bk := flag.String("bk", os.Getenv(bing.EnvKey), "Bing API key. Used only if engine is bing.")
+	manifest := flag.String("manifest", os.Getenv(static.EnvManifest), "Path to a JSON manifest mapping queries to results. Used only if engine is static.")
 	q := flag.String("q", "", "Optional query to search for.")
 	t := flag.String("t", "undefined", "Image type to search for (clipart|face|lineart|news|photo).")
 	s := flag.String("s", "undefined", "Image size to search for (huge|icon|large|medium|small|xlarge|xxlarge).")
 	i := flag.String("i", "-", "Input file containing the words to retrive the image of. csv encoded, use the \"c\" flag to select the proper column. If \"q\" is present, this flag is ignored. Use - for stdin.")
 	c := flag.Int("c", 3, "If \"i\" is used, selects the column which will be used as word input.")
+	cacheDir := flag.String("cache-dir", "", "Directory to persist validated search results under, shared across runs. Left empty, the cache is kept in memory only.")
+	cacheEntries := flag.Int("cache-max-entries", 10000, "Maximum number of distinct queries the cache keeps at once.")
+	cacheTTL := flag.Duration("cache-ttl", 7*24*time.Hour, "How long a cached query is kept since it was last used.")
+	cacheMaxBytes := flag.Int64("cache-max-bytes", 256<<20, "Maximum size, in bytes, of -cache-dir.")
+	cacheFreshness := flag.Duration("cache-freshness", time.Hour, "How long a validated image link is trusted before being re-checked.")
 	flag.Parse()
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -328,10 +238,33 @@ func main() {
 		cancel()
 	}()
 
-	gsc := google.NewSC(*k, *cx)
+	// Engine-specific credentials are threaded through as environment
+	// variables, so a backend package only ever needs a bare New(ctx)
+	// to read the configuration that applies to it.
+	os.Setenv(google.EnvKey, *k)
+	os.Setenv(google.EnvCx, *cx)
+	os.Setenv(bing.EnvKey, *bk)
+	os.Setenv(static.EnvManifest, *manifest)
+
+	sc, err := search.New(ctx, *e)
+	if err != nil {
+		exitf("unable to initiate search engine: %v", err)
+	}
+
+	cm, err := cache.NewManager(cache.Config{
+		Dir:             *cacheDir,
+		MaxEntries:      *cacheEntries,
+		TTL:             *cacheTTL,
+		MaxDiskBytes:    *cacheMaxBytes,
+		FreshnessWindow: *cacheFreshness,
+	})
+	if err != nil {
+		exitf("unable to initiate result cache: %v", err)
+	}
+
 	if *q != "" {
-		handleQSearch(ctx, gsc, *q, google.FilterImgType(*t), google.FilterImgSize(*s))
+		handleQSearch(ctx, sc, cm, *q, search.ImgType(*t), search.ImgSize(*s))
 	} else {
-		handleSSearch(ctx, gsc, *i, *c, google.FilterImgType(*t), google.FilterImgSize(*s))
+		handleSSearch(ctx, sc, cm, *i, *c, search.ImgType(*t), search.ImgSize(*s))
 	}
 }
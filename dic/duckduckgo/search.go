@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+// Package duckduckgo implements search.Client against DuckDuckGo's
+// (unofficial, undocumented) image search endpoint: it has no API key,
+// so it is useful as a free fallback when the other backends run out of
+// quota.
+package duckduckgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/discursive-image/dic/search"
+)
+
+func init() {
+	search.Register("duckduckgo", func(ctx context.Context) (search.Client, error) {
+		return NewSC(), nil
+	})
+}
+
+// SC is a DuckDuckGo search client, implementing search.Client.
+// Initialize it using NewSC.
+type SC struct{}
+
+var _ search.Client = (*SC)(nil)
+
+// NewSC returns a new DuckDuckGo search client.
+func NewSC() *SC {
+	return &SC{}
+}
+
+var client = &http.Client{}
+
+// vqdPattern extracts the "vqd" token DuckDuckGo's image search embeds
+// in the regular search results page; i.js rejects requests that don't
+// carry it.
+var vqdPattern = regexp.MustCompile(`vqd=['"]([\d-]+)['"]`)
+
+func (c *SC) vqd(ctx context.Context, q string) (string, error) {
+	u, err := url.Parse("https://duckduckgo.com/")
+	if err != nil {
+		return "", fmt.Errorf("unable to parse base url: %w", err)
+	}
+	v := url.Values{}
+	v.Set("q", q)
+	v.Set("iax", "images")
+	v.Set("ia", "images")
+	u.RawQuery = v.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to build vqd request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to contact duckduckgo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body := make([]byte, 0, 64*1024)
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		body = append(body, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+
+	m := vqdPattern.FindSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("unable to find vqd token in duckduckgo response")
+	}
+	return string(m[1]), nil
+}
+
+type isr struct {
+	Image     string `json:"image"`
+	Thumbnail string `json:"thumbnail"`
+	URL       string `json:"url"`
+}
+
+func (i *isr) result() *search.Result {
+	return &search.Result{
+		Link:        i.Image,
+		Thumb:       i.Thumbnail,
+		ContextLink: i.URL,
+	}
+}
+
+func decodeISR(r io.Reader) ([]*isr, error) {
+	var res struct {
+		Results []*isr `json:"results"`
+	}
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return nil, fmt.Errorf("unable to decode response: %w", err)
+	}
+	return res.Results, nil
+}
+
+// SearchImages searches DuckDuckGo for images. opts is accepted for
+// search.Client compliance but ignored: DuckDuckGo's endpoint does not
+// expose the type/size filters the other backends do.
+func (c *SC) SearchImages(ctx context.Context, q string, opts ...search.Option) ([]*search.Result, error) {
+	vqd, err := c.vqd(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse("https://duckduckgo.com/i.js")
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse base url: %w", err)
+	}
+	v := url.Values{}
+	v.Set("l", "us-en")
+	v.Set("o", "json")
+	v.Set("q", q)
+	v.Set("vqd", vqd)
+	u.RawQuery = v.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build duckduckgo search request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to contact duckduckgo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("duckduckgo search returned %s", resp.Status)
+	}
+
+	items, err := decodeISR(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*search.Result, len(items))
+	for i, item := range items {
+		results[i] = item.result()
+	}
+	return results, nil
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+package duckduckgo
+
+import (
+	"strings"
+	"testing"
+)
+
+var ddgResponse = `{
+    "results": [
+        {
+            "height": 1200,
+            "image": "https://example.com/cat.jpg",
+            "source": "Bing",
+            "thumbnail": "https://example.com/cat-thumb.jpg",
+            "title": "A cat",
+            "url": "https://example.com/cats",
+            "width": 1600
+        }
+    ],
+    "next": "i.js?l=us-en&o=json&q=cats&vqd=1-234&p=1&s=100"
+}
+`
+
+func TestDecodeISR(t *testing.T) {
+	items, err := decodeISR(strings.NewReader(ddgResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("unexpected items count: %d", len(items))
+	}
+
+	got := items[0].result()
+	if got.Link != "https://example.com/cat.jpg" {
+		t.Errorf("Link = %q, want https://example.com/cat.jpg", got.Link)
+	}
+	if got.Thumb != "https://example.com/cat-thumb.jpg" {
+		t.Errorf("Thumb = %q, want https://example.com/cat-thumb.jpg", got.Thumb)
+	}
+	if got.ContextLink != "https://example.com/cats" {
+		t.Errorf("ContextLink = %q, want https://example.com/cats", got.ContextLink)
+	}
+}
+
+func TestVqdPattern(t *testing.T) {
+	body := []byte(`... vqd='1-234-567' ...`)
+	m := vqdPattern.FindSubmatch(body)
+	if m == nil {
+		t.Fatal("vqdPattern did not match")
+	}
+	if got := string(m[1]); got != "1-234-567" {
+		t.Errorf("vqd token = %q, want 1-234-567", got)
+	}
+}
@@ -2,6 +2,8 @@
 //
 // SPDX-License-Identifier: MIT
 
+// Package google implements search.Client against Google's Custom
+// Search JSON API.
 package google
 
 import (
@@ -11,9 +13,28 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+
+	"github.com/discursive-image/dic/search"
+)
+
+// Environment variables the "google" backend registered with
+// search.Register reads its key/cx from, mirroring how sgtr's
+// google.Client resolves GOOGLE_APPLICATION_CREDENTIALS. NewSC itself
+// only uses the values it is given.
+const (
+	EnvKey = "GOOGLE_SEARCH_KEY"
+	EnvCx  = "GOOGLE_SEARCH_CX"
 )
 
-// SC is a google search client. Initialize it using NewSC.
+func init() {
+	search.Register("google", func(ctx context.Context) (search.Client, error) {
+		return NewSC(os.Getenv(EnvKey), os.Getenv(EnvCx)), nil
+	})
+}
+
+// SC is a google search client, implementing search.Client. Initialize
+// it using NewSC.
 type SC struct {
 	// Authentication key.
 	// https://developers.google.com/custom-search/v1/overview
@@ -23,6 +44,8 @@ type SC struct {
 	Cx string
 }
 
+var _ search.Client = (*SC)(nil)
+
 // NewSC returns a new google search client.
 func NewSC(k, cx string) *SC {
 	return &SC{
@@ -63,6 +86,19 @@ type ISR struct {
 	DisplayLink string `json:"displayLink"`
 }
 
+// result converts an ISR into the backend-agnostic search.Result.
+func (i *ISR) result() *search.Result {
+	r := &search.Result{
+		Link: i.Link,
+		Mime: i.Mime,
+	}
+	if i.Image != nil {
+		r.Thumb = i.Image.ThumbLink
+		r.ContextLink = i.Image.ContextLink
+	}
+	return r
+}
+
 func decodeISR(r io.Reader) ([]*ISR, error) {
 	// Decode response.
 	var res struct {
@@ -95,17 +131,6 @@ const (
 	ImgTypePhoto   = "photo"
 )
 
-func FilterImgType(s string) func(url.Values) {
-	return func(v url.Values) {
-		switch s {
-		case "clipart", "face", "lineart", "news", "photo":
-			v.Set("imgType", s)
-		default:
-			v.Del("imgType")
-		}
-	}
-}
-
 const (
 	ImgSizeHuge      = "huge"
 	ImgSizeIcon      = "icon"
@@ -117,31 +142,37 @@ const (
 	ImgSizeUndefined = "undefined"
 )
 
-func FilterImgSize(s string) func(url.Values) {
-	return func(v url.Values) {
-		switch s {
-		case "huge", "icon", "large", "medium", "small", "xlarge", "xxlarge":
-			v.Set("imgSize", s)
-		default:
-			v.Del("imgSize")
-		}
+// values turns a search.Filter into the query parameters the Custom
+// Search API expects, leaving imgType/imgSize unset for values it
+// doesn't recognize.
+func values(f search.Filter) url.Values {
+	v := url.Values{}
+	switch f.ImgType {
+	case ImgTypeClipart, ImgTypeFace, ImgTypeLineart, ImgTypeNews, ImgTypePhoto:
+		v.Set("imgType", f.ImgType)
+	}
+	switch f.ImgSize {
+	case ImgSizeHuge, ImgSizeIcon, ImgSizeLarge, ImgSizeMedium, ImgSizeSmall, ImgSizeXLarge, ImgSizeXXLarge:
+		v.Set("imgSize", f.ImgSize)
 	}
+	return v
 }
 
 var client = &http.Client{}
 
 // SearchImages searches google for images.
-func (c *SC) SearchImages(ctx context.Context, q string, opts ...func(url.Values)) ([]*ISR, error) {
+func (c *SC) SearchImages(ctx context.Context, q string, opts ...search.Option) ([]*search.Result, error) {
 	// Validate client
 	if err := c.Validate(); err != nil {
 		return nil, err
 	}
 
 	// Prepare URL.
-	v := url.Values{}
-	for _, f := range opts {
-		f(v)
+	var f search.Filter
+	for _, o := range opts {
+		o(&f)
 	}
+	v := values(f)
 	v.Set("key", c.Key)
 	v.Set("cx", c.Cx)
 	v.Set("searchType", "image")
@@ -170,5 +201,14 @@ func (c *SC) SearchImages(ctx context.Context, q string, opts ...func(url.Values
 	if resp.StatusCode != http.StatusOK {
 		return nil, decodeError(resp.Body)
 	}
-	return decodeISR(resp.Body)
+	items, err := decodeISR(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*search.Result, len(items))
+	for i, item := range items {
+		results[i] = item.result()
+	}
+	return results, nil
 }
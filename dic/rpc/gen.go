@@ -0,0 +1,7 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+package rpc
+
+//go:generate make -C .. proto
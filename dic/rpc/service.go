@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+// Package rpc implements rpcpb.SearchServiceServer on top of
+// search.Client and cache.Manager, so that dicd can expose the same
+// Search RPC described in search.proto. rpcpb itself is generated from
+// that file with `protoc -I. --go_out=plugins=grpc:. rpc/search.proto`
+// (see the proto Makefile target); run it before building this package.
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/discursive-image/dic/cache"
+	"github.com/discursive-image/dic/rpc/rpcpb"
+	"github.com/discursive-image/dic/search"
+)
+
+// maxcc bounds how many queries a single Search call resolves
+// concurrently, mirroring the CLI's own ImageRequest semaphore.
+const maxcc = 10
+
+// Service implements rpcpb.SearchServiceServer against sc, caching
+// validated results in cm exactly as dic's CLI does.
+type Service struct {
+	rpcpb.UnimplementedSearchServiceServer
+
+	sc search.Client
+	cm *cache.Manager
+}
+
+// NewService returns a Service resolving queries against sc, caching
+// results in cm.
+func NewService(sc search.Client, cm *cache.Manager) *Service {
+	return &Service{sc: sc, cm: cm}
+}
+
+func filterFromPB(f *rpcpb.Filter) []search.Option {
+	if f == nil {
+		return nil
+	}
+	return []search.Option{search.ImgType(f.ImgType), search.ImgSize(f.ImgSize)}
+}
+
+// Search resolves every SearchRequest it receives against a Ref pulled
+// out of s.cm (populated through s.sc.SearchImages on a cache miss), up
+// to maxcc at a time, sending one SearchResponse per request as soon as
+// it resolves; responses may arrive out of order relative to requests,
+// which is why both carry RequestId.
+func (s *Service) Search(stream rpcpb.SearchService_SearchServer) error {
+	ctx := stream.Context()
+	sem := make(chan struct{}, maxcc)
+	respc := make(chan *rpcpb.SearchResponse)
+	sendErrc := make(chan error, 1)
+	senderDone := make(chan struct{})
+	var wg sync.WaitGroup
+
+	go func() {
+		defer close(senderDone)
+		var sendErr error
+		for resp := range respc {
+			// Keep draining respc even after a failed Send, so that
+			// resolve goroutines still in flight never block forever
+			// writing to it.
+			if sendErr != nil {
+				continue
+			}
+			if err := stream.Send(resp); err != nil {
+				sendErr = err
+				sendErrc <- err
+			}
+		}
+	}()
+
+	var recvErr error
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			recvErr = err
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(req *rpcpb.SearchRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			respc <- s.resolve(ctx, req)
+		}(req)
+	}
+
+	wg.Wait()
+	close(respc)
+	<-senderDone
+
+	select {
+	case err := <-sendErrc:
+		return err
+	default:
+	}
+	if recvErr != nil && recvErr != io.EOF {
+		return recvErr
+	}
+	return nil
+}
+
+func (s *Service) resolve(ctx context.Context, req *rpcpb.SearchRequest) *rpcpb.SearchResponse {
+	ref, ok := s.cm.Get(req.Query)
+	if !ok {
+		items, err := s.sc.SearchImages(ctx, req.Query, filterFromPB(req.Filter)...)
+		if err != nil {
+			return &rpcpb.SearchResponse{RequestId: req.RequestId, Error: err.Error()}
+		}
+		if len(items) == 0 {
+			return &rpcpb.SearchResponse{RequestId: req.RequestId, Error: "no results"}
+		}
+		ref = s.cm.Put(req.Query, items)
+	}
+	defer ref.Release()
+
+	image := ref.Next()
+	if image == nil {
+		return &rpcpb.SearchResponse{RequestId: req.RequestId, Error: fmt.Sprintf("no valid image found for %q", req.Query)}
+	}
+	return &rpcpb.SearchResponse{
+		RequestId: req.RequestId,
+		Result: &rpcpb.Result{
+			Link:        image.Link,
+			Thumb:       image.Thumb,
+			Mime:        image.Mime,
+			ContextLink: image.ContextLink,
+		},
+	}
+}
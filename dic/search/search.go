@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+// Package search defines the image search abstraction dic's backends
+// implement, mirroring the Transcriber/backend-registry pattern used by
+// the sibling sgtr binary: callers depend on Client rather than on a
+// concrete provider, so a new engine is a self-contained package
+// selectable by name through New.
+package search
+
+import (
+	"context"
+	"fmt"
+)
+
+// Result is a single image search hit, shaped the same way regardless
+// of which backend produced it.
+type Result struct {
+	// Link is the URL of the full-size image.
+	Link string
+	// Thumb is the URL of a (usually smaller) thumbnail, when the
+	// backend provides one.
+	Thumb string
+	// Mime is the image's MIME type, e.g. "image/jpeg", when known.
+	Mime string
+	// ContextLink is the URL of the page the image was found on.
+	ContextLink string
+}
+
+// Filter narrows an image search. Backends interpret the fields they
+// understand and ignore the rest.
+type Filter struct {
+	// ImgType restricts results to a given image type, e.g. "photo" or
+	// "clipart".
+	ImgType string
+	// ImgSize restricts results to a given image size, e.g. "medium" or
+	// "xlarge".
+	ImgSize string
+}
+
+// Option mutates a Filter. Use ImgType/ImgSize to build one.
+type Option func(*Filter)
+
+// ImgType sets Filter.ImgType.
+func ImgType(s string) Option {
+	return func(f *Filter) { f.ImgType = s }
+}
+
+// ImgSize sets Filter.ImgSize.
+func ImgSize(s string) Option {
+	return func(f *Filter) { f.ImgSize = s }
+}
+
+// Client is implemented by every image search backend dic can use.
+type Client interface {
+	// SearchImages searches for q, returning matching Results ordered
+	// by relevance, most relevant first.
+	SearchImages(ctx context.Context, q string, opts ...Option) ([]*Result, error)
+}
+
+// Factory builds a Client. Backends are expected to read whatever
+// credentials or configuration they need from the environment, the same
+// way sgtr's google.Client resolves GOOGLE_APPLICATION_CREDENTIALS.
+type Factory func(ctx context.Context) (Client, error)
+
+var backends = map[string]Factory{}
+
+// Register makes a Client backend available under name. Backend
+// packages are expected to call it from an init function, so that
+// importing them for their side effect is enough to make them
+// selectable through New.
+func Register(name string, f Factory) {
+	backends[name] = f
+}
+
+// New returns the Client backend registered under name, or an error if
+// none matches.
+func New(ctx context.Context, name string) (Client, error) {
+	f, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported search engine %s", name)
+	}
+	return f(ctx)
+}
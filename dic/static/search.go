@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+// Package static implements search.Client on top of a JSON manifest
+// file instead of a live search engine, so CI and offline runs get
+// reproducible results without burning any API quota.
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/discursive-image/dic/search"
+)
+
+// EnvManifest is the environment variable the "static"/"file" backend
+// registered with search.Register reads the manifest path from. NewSC
+// itself only reads the path it is given.
+const EnvManifest = "STATIC_SEARCH_MANIFEST"
+
+func init() {
+	factory := func(ctx context.Context) (search.Client, error) {
+		return NewSC(os.Getenv(EnvManifest))
+	}
+	// "static" and "file" are the same backend, registered under both
+	// names so either reads naturally from -e.
+	search.Register("static", factory)
+	search.Register("file", factory)
+}
+
+// entry is the JSON shape of a single manifest result.
+type entry struct {
+	Link        string `json:"link"`
+	Thumb       string `json:"thumb"`
+	Mime        string `json:"mime"`
+	ContextLink string `json:"context_link"`
+}
+
+// SC is a search.Client backed by a manifest mapping queries to the
+// results that should be returned for them, loaded once at
+// construction. Initialize it using NewSC.
+type SC struct {
+	index map[string][]*search.Result
+}
+
+var _ search.Client = (*SC)(nil)
+
+// NewSC reads the JSON manifest at path, a map of query string to the
+// list of results SearchImages should return for it, e.g.:
+//
+//	{"cats": [{"link": "https://example.com/cat.jpg"}]}
+func NewSC(path string) (*SC, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %w", err)
+	}
+
+	var manifest map[string][]entry
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to decode manifest: %w", err)
+	}
+
+	index := make(map[string][]*search.Result, len(manifest))
+	for q, entries := range manifest {
+		results := make([]*search.Result, len(entries))
+		for i, e := range entries {
+			results[i] = &search.Result{
+				Link:        e.Link,
+				Thumb:       e.Thumb,
+				Mime:        e.Mime,
+				ContextLink: e.ContextLink,
+			}
+		}
+		index[q] = results
+	}
+	return &SC{index: index}, nil
+}
+
+// SearchImages returns the results the manifest associates with q, or
+// an error if q is not present in it. opts is accepted for
+// search.Client compliance but ignored, since the manifest already
+// pins down the exact results to return.
+func (c *SC) SearchImages(ctx context.Context, q string, opts ...search.Option) ([]*search.Result, error) {
+	results, ok := c.index[q]
+	if !ok {
+		return nil, fmt.Errorf("no results for %q in manifest", q)
+	}
+	return results, nil
+}
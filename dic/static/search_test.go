@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+package static
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeManifest(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestNewSCAndSearchImages(t *testing.T) {
+	path := writeManifest(t, `{
+		"cats": [
+			{"link": "https://example.com/cat.jpg", "thumb": "https://example.com/cat-thumb.jpg", "mime": "image/jpeg", "context_link": "https://example.com/cats"}
+		]
+	}`)
+
+	sc, err := NewSC(path)
+	if err != nil {
+		t.Fatalf("NewSC: %v", err)
+	}
+
+	results, err := sc.SearchImages(context.Background(), "cats")
+	if err != nil {
+		t.Fatalf("SearchImages: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("unexpected results count: %d", len(results))
+	}
+	if results[0].Link != "https://example.com/cat.jpg" {
+		t.Errorf("Link = %q, want https://example.com/cat.jpg", results[0].Link)
+	}
+
+	if _, err := sc.SearchImages(context.Background(), "dogs"); err == nil {
+		t.Fatal("SearchImages(\"dogs\") = nil error, want one for a query missing from the manifest")
+	}
+}
+
+func TestNewSCMissingFile(t *testing.T) {
+	if _, err := NewSC(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("NewSC with a missing manifest = nil error, want one")
+	}
+}
@@ -0,0 +1,298 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+// Package fetch lets diroom pull its audio from a remote URL (YouTube,
+// direct media or HLS) instead of only consuming os.Stdin: it resolves
+// the best audio-only stream and transcodes it to 16kHz mono WAV via
+// ffmpeg, reporting progress as it goes.
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage identifies the phase a Fetch is currently in.
+type Stage int
+
+const (
+	StageResolving Stage = iota
+	StageDownloading
+	StageDone
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageResolving:
+		return "resolving"
+	case StageDownloading:
+		return "downloading"
+	case StageDone:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
+
+// ProgressEvent describes a point-in-time snapshot of a Fetch, suitable
+// for printing by a CLI. Done/Total are the elapsed/total duration of the
+// source audio, not byte counts: ffmpeg's own "time=" progress reports the
+// duration of the *decoded* audio it has written so far, which is the only
+// quantity directly comparable to Total (the source's reported duration);
+// the compressed source size youtube-dl reports and the decoded WAV's size
+// are unrelated units and don't produce a meaningful ratio against each
+// other. Total is 0 when the source does not report its duration upfront.
+type ProgressEvent struct {
+	Stage Stage
+	Done  time.Duration
+	Total time.Duration
+}
+
+// stream describes a resolved, directly fetchable audio stream.
+type stream struct {
+	url      string
+	duration time.Duration
+}
+
+// VideoFetchService resolves a URL to its best audio-only stream and
+// transcodes it to 16kHz mono WAV, using the system's youtube-dl and
+// ffmpeg binaries. Initialize it with NewVideoFetchService.
+type VideoFetchService struct {
+	ytdlPath   string
+	ffmpegPath string
+}
+
+// YtdlPath overrides the youtube-dl binary used to resolve stream URLs.
+// Defaults to "youtube-dl".
+func YtdlPath(path string) func(*VideoFetchService) {
+	return func(s *VideoFetchService) {
+		s.ytdlPath = path
+	}
+}
+
+// FfmpegPath overrides the ffmpeg binary used to transcode the resolved
+// stream. Defaults to "ffmpeg".
+func FfmpegPath(path string) func(*VideoFetchService) {
+	return func(s *VideoFetchService) {
+		s.ffmpegPath = path
+	}
+}
+
+func NewVideoFetchService(opts ...func(*VideoFetchService)) *VideoFetchService {
+	s := &VideoFetchService{
+		ytdlPath:   "youtube-dl",
+		ffmpegPath: "ffmpeg",
+	}
+	for _, f := range opts {
+		f(s)
+	}
+	return s
+}
+
+// Fetch resolves rawURL and starts transcoding it in the background,
+// storing the resulting WAV at root/fetch.wav. If that file already
+// exists (e.g. the room's pipeline is being restarted) it is reused
+// untouched and reported as already done, instead of being fetched
+// again.
+//
+// Drain the returned Fetch's progress via Next before calling Reader.
+func (s *VideoFetchService) Fetch(ctx context.Context, rawURL, root string) *Fetch {
+	f := &Fetch{
+		path:     filepath.Join(root, "fetch.wav"),
+		progress: make(chan ProgressEvent, 8),
+	}
+
+	if fi, err := os.Stat(f.path); err == nil && fi.Size() > 0 {
+		go func() {
+			defer close(f.progress)
+			f.progress <- ProgressEvent{Stage: StageDone}
+		}()
+		return f
+	}
+
+	go f.run(ctx, s, rawURL)
+	return f
+}
+
+// Fetch represents an in-flight (or already reused) audio fetch.
+type Fetch struct {
+	path     string
+	progress chan ProgressEvent
+	err      error
+}
+
+// Progress returns the channel of progress events for this fetch. It is
+// closed once the fetch is done; check Err afterwards.
+func (f *Fetch) Progress() <-chan ProgressEvent {
+	return f.progress
+}
+
+// Next blocks until the next progress event, or the fetch has finished
+// (ok == false). Callers poll it in a loop to print progress while the
+// fetch runs in the background.
+func (f *Fetch) Next() (ProgressEvent, bool) {
+	ev, ok := <-f.progress
+	return ev, ok
+}
+
+// Err returns the error that terminated the fetch, if any. It is only
+// meaningful once Progress/Next has been fully drained.
+func (f *Fetch) Err() error {
+	return f.err
+}
+
+// Reader opens the fetched WAV file for reading. Call it only after the
+// fetch has completed without error.
+func (f *Fetch) Reader() (*os.File, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	r, err := os.Open(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open fetched wav: %w", err)
+	}
+	return r, nil
+}
+
+func (f *Fetch) run(ctx context.Context, s *VideoFetchService, rawURL string) {
+	defer close(f.progress)
+
+	f.progress <- ProgressEvent{Stage: StageResolving}
+	st, err := s.resolve(ctx, rawURL)
+	if err != nil {
+		f.err = err
+		return
+	}
+
+	tmp := f.path + ".part"
+	if err := s.transcode(ctx, st, tmp, f.progress); err != nil {
+		os.Remove(tmp)
+		f.err = err
+		return
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		f.err = fmt.Errorf("unable to finalize fetched wav: %w", err)
+	}
+}
+
+// resolve asks youtube-dl for the direct URL of the best audio-only
+// stream backing rawURL. youtube-dl's generic extractor also covers
+// plain direct media links and HLS playlists, so this is enough to
+// support all three of diroom's -url sources.
+func (s *VideoFetchService) resolve(ctx context.Context, rawURL string) (*stream, error) {
+	cmd := exec.CommandContext(ctx, s.ytdlPath, "-f", "bestaudio/best", "-j", rawURL)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve audio stream for %s: %w", rawURL, err)
+	}
+
+	var info struct {
+		URL      string  `json:"url"`
+		Duration float64 `json:"duration"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("unable to decode stream info for %s: %w", rawURL, err)
+	}
+
+	return &stream{url: info.URL, duration: time.Duration(info.Duration * float64(time.Second))}, nil
+}
+
+// transcode pulls st's url straight into ffmpeg, writing 16kHz mono WAV
+// to out, and reports download progress parsed off ffmpeg's own stderr
+// "time=" updates: the elapsed duration of decoded audio written so far,
+// the only quantity directly comparable to st.duration (see ProgressEvent).
+func (s *VideoFetchService) transcode(ctx context.Context, st *stream, out string, progress chan<- ProgressEvent) error {
+	cmd := exec.CommandContext(ctx, s.ffmpegPath,
+		"-y",
+		"-i", st.url,
+		"-vn", "-ac", "1", "-ar", "16000",
+		"-f", "wav", out,
+	)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("unable to open ffmpeg's stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to start ffmpeg: %w", err)
+	}
+
+	progress <- ProgressEvent{Stage: StageDownloading, Total: st.duration}
+
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanCRLF)
+	for scanner.Scan() {
+		done, ok := parseFfmpegTime(scanner.Text())
+		if !ok {
+			continue
+		}
+		progress <- ProgressEvent{Stage: StageDownloading, Done: done, Total: st.duration}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg exited with error: %w", err)
+	}
+	progress <- ProgressEvent{Stage: StageDone, Done: st.duration, Total: st.duration}
+	return nil
+}
+
+// scanCRLF is a bufio.SplitFunc that treats both "\n" and ffmpeg's
+// "\r"-terminated progress updates as line boundaries.
+func scanCRLF(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[0:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// parseFfmpegTime extracts the elapsed encode duration out of an ffmpeg
+// progress line such as "size=    1234kB time=00:00:05.12 bitrate=
+// 128.0kbits/s".
+func parseFfmpegTime(line string) (time.Duration, bool) {
+	idx := strings.Index(line, "time=")
+	if idx < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(line[idx+len("time="):])
+	if len(fields) == 0 {
+		return 0, false
+	}
+	return parseFfmpegDuration(fields[0])
+}
+
+// parseFfmpegDuration parses ffmpeg's "HH:MM:SS.ms" timestamp format.
+func parseFfmpegDuration(s string) (time.Duration, bool) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		return 0, false
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, false
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, false
+	}
+	sec, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, false
+	}
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second))
+	return d, true
+}
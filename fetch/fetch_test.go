@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2020 Jecoz
+//
+// SPDX-License-Identifier: MIT
+
+package fetch
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFfmpegTime(t *testing.T) {
+	line := "size=    1234kB time=00:01:05.12 bitrate= 128.0kbits/s speed=1.01x"
+	got, ok := parseFfmpegTime(line)
+	if !ok {
+		t.Fatalf("expected a time to be found in %q", line)
+	}
+	if want := time.Minute + 5*time.Second + 120*time.Millisecond; got != want {
+		t.Fatalf("unexpected time: got %v, want %v", got, want)
+	}
+}
+
+func TestParseFfmpegTimeNoMatch(t *testing.T) {
+	if _, ok := parseFfmpegTime("frame=  120 fps=30"); ok {
+		t.Fatalf("expected no time to be found")
+	}
+}
+
+// TestTranscodeProgressAgainstDuration feeds a simulated ffmpeg stderr
+// stream (CR-terminated progress lines, as ffmpeg actually writes them)
+// through the same scanCRLF split func transcode uses, and checks that
+// the resulting Done values climb towards, and never exceed, a Total
+// taken from the source's own reported duration. This is the regression
+// test for reporting progress against two incompatible units (compressed
+// source bytes vs. decoded WAV bytes): with Done/Total both expressed as
+// durations, a done/total ratio is finally meaningful.
+func TestTranscodeProgressAgainstDuration(t *testing.T) {
+	const stderr = "frame=1\r" +
+		"size=     512kB time=00:00:02.50 bitrate= 128.0kbits/s\r" +
+		"size=    1024kB time=00:00:05.00 bitrate= 128.0kbits/s\n" +
+		"size=    1536kB time=00:00:07.50 bitrate= 128.0kbits/s\r"
+
+	total := 10 * time.Second
+
+	scanner := bufio.NewScanner(strings.NewReader(stderr))
+	scanner.Split(scanCRLF)
+
+	var last time.Duration
+	var events int
+	for scanner.Scan() {
+		done, ok := parseFfmpegTime(scanner.Text())
+		if !ok {
+			continue
+		}
+		if done < last {
+			t.Fatalf("Done went backwards: %v after %v", done, last)
+		}
+		if done > total {
+			t.Fatalf("Done %v exceeds Total %v", done, total)
+		}
+		last = done
+		events++
+	}
+	if events != 3 {
+		t.Fatalf("got %d progress events, want 3", events)
+	}
+	if want := 7500 * time.Millisecond; last != want {
+		t.Fatalf("final Done = %v, want %v", last, want)
+	}
+}
@@ -5,13 +5,17 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 
+	"github.com/discursive-image/diroom/fetch"
 	"github.com/google/uuid"
 	"gopkg.in/pipe.v2"
 	"gopkg.in/yaml.v2"
@@ -45,6 +49,8 @@ func main() {
 	id := flag.String("id", uuid.New().String(), "Room identifier. It is also used as reference when storing data.")
 	p := flag.Int("p", 7745, "Discorsive Image server listening port.")
 	cache := flag.Bool("cache", false, "Enable caching results with REDIS.")
+	input := flag.String("input", "stdin", "Audio source: \"stdin\" to read from standard input, or \"url\" to fetch and transcode the stream given via -url.")
+	url := flag.String("url", "", "URL (YouTube, direct media or HLS) to fetch audio from. Only used when -input=url.")
 	flag.Parse()
 
 	// Read configuration.
@@ -91,9 +97,14 @@ func main() {
 	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", config.AppCreds)
 	os.Setenv("PATH", filepath.Dir(os.Args[0]))
 
+	src, err := source(*input, *url, root)
+	if err != nil {
+		fatalf("unable to prepare input source: %v", err)
+	}
+
 	logf("server is listening on %d", *p)
 	l := pipe.Line(
-		pipe.Read(os.Stdin),
+		pipe.Read(src),
 		pipe.Exec("sgtr", "-s", "-lang", *lang, "-id", *id, "-lp", filepath.Join(root, "sgtr.log")),
 		pipe.TeeWriteFile(filepath.Join(root, "transcript.trr"), os.ModePerm),
 		pipe.Exec("dic", dicArgs...),
@@ -104,3 +115,31 @@ func main() {
 		fatalf("unable to run pipe: %v", err)
 	}
 }
+
+// source returns the audio stream the pipeline should read from,
+// according to input ("stdin" or "url"). In the "url" case, rawURL is
+// fetched and transcoded to 16kHz mono WAV under root, so that a
+// restarted pipeline can pick up the same file instead of fetching it
+// again.
+func source(input, rawURL, root string) (io.Reader, error) {
+	switch input {
+	case "stdin":
+		return os.Stdin, nil
+	case "url":
+		if rawURL == "" {
+			return nil, fmt.Errorf("-url is required when -input=url")
+		}
+
+		svc := fetch.NewVideoFetchService()
+		ft := svc.Fetch(context.Background(), rawURL, root)
+		for ev, ok := ft.Next(); ok; ev, ok = ft.Next() {
+			logf("fetching %s: %s (%v/%v)", rawURL, ev.Stage, ev.Done, ev.Total)
+		}
+		if err := ft.Err(); err != nil {
+			return nil, fmt.Errorf("unable to fetch %s: %w", rawURL, err)
+		}
+		return ft.Reader()
+	default:
+		return nil, fmt.Errorf("unsupported -input value %q", input)
+	}
+}
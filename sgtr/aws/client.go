@@ -5,12 +5,23 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 
+	"git.keepinmind.info/subgensdk/sgtr/blob"
+	"git.keepinmind.info/subgensdk/sgtr/blob/metrics"
+	"git.keepinmind.info/subgensdk/sgtr/tr"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+func init() {
+	tr.Register("aws", func(ctx context.Context, region string) (tr.Transcriber, error) {
+		return NewClient(region)
+	})
+}
+
 type Client struct {
 	sess *session.Session
 }
@@ -25,3 +36,19 @@ func NewClient(region string) (*Client, error) {
 
 	return &Client{sess}, nil
 }
+
+// bucketFor resolves the blob.Bucket req's transcription job should stage
+// its input (and vocabulary) through: req.Storage's backend when set,
+// falling back to this client's own S3 bucket otherwise. The returned
+// bucket is always wrapped with metrics.Instrument, so storage usage is
+// tracked regardless of which backend ends up serving the room.
+func (c *Client) bucketFor(ctx context.Context, req *tr.Req) (blob.Bucket, error) {
+	if req.Storage.Backend != "" {
+		b, err := blob.New(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		return metrics.Instrument(b, prometheus.DefaultRegisterer, req.Storage.Backend, req.Storage.Bucket), nil
+	}
+	return metrics.Instrument(c.NewBkt(req.Bkt), prometheus.DefaultRegisterer, "s3", req.Bkt), nil
+}
@@ -6,27 +6,129 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"time"
 
+	"git.keepinmind.info/subgensdk/sgtr/blob"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
+func init() {
+	blob.Register("s3", func(ctx context.Context, cfg blob.Config) (blob.Bucket, error) {
+		c, err := NewClient(cfg.Region)
+		if err != nil {
+			return nil, err
+		}
+		return c.NewBkt(cfg.Bucket), nil
+	})
+}
+
+// Defaults applied by NewBkt, borrowed from Arvados' keepstore S3 volume:
+// generous enough to ride out S3's eventual-consistency window without
+// letting a stuck request hang the pipeline forever.
+//
+// defaultRaceWindow is 0 (hard-delete immediately, same as before Bkt grew
+// a trash tier) because none of today's callers are configured with a
+// RaceWindow of their own, and they delete scratch objects seconds after
+// creating them as part of the same job: a window with any of this
+// package's old 24h default would tag-and-keep every one of those deletes
+// instead, forever, since nothing calls EmptyTrash. Pass RaceWindow to
+// NewBkt to opt a bucket into deferred trashing once something reclaims it.
+const (
+	defaultReadTimeout    = 10 * time.Second
+	defaultConnectTimeout = time.Second
+	defaultRaceWindow     = 0
+	defaultTrashLifetime  = 14 * 24 * time.Hour
+)
+
+// trashTagKey is the object tag Delete writes when it soft-deletes a key,
+// and the one EmptyTrash looks for when reclaiming it later.
+const trashTagKey = "expires-at"
+
+// ErrS3TrashDisabled is returned by Bkt.Delete when key is younger than
+// the bucket's RaceWindow and UnsafeDelete is not set: the object has
+// been tagged for deferred removal instead of being hard-deleted, so a
+// concurrent retry of the same upload can't race with it disappearing.
+var ErrS3TrashDisabled = errors.New("aws: object younger than race window, soft-deleted instead")
+
+// Bkt is an S3 bucket, implementing blob.Bucket.
 type Bkt struct {
 	client *s3.S3
 	name   string
+
+	readTimeout    time.Duration
+	connectTimeout time.Duration
+	raceWindow     time.Duration
+	trashLifetime  time.Duration
+	unsafeDelete   bool
+}
+
+var _ blob.Bucket = (*Bkt)(nil)
+
+// ReadTimeout overrides the default HTTP response read timeout applied
+// to every S3 request the Bkt issues.
+func ReadTimeout(d time.Duration) func(*Bkt) {
+	return func(b *Bkt) { b.readTimeout = d }
 }
 
-func (c *Client) NewBkt(name string) *Bkt {
-	return &Bkt{
-		client: s3.New(c.sess),
-		name:   name,
+// ConnectTimeout overrides the default TCP connect timeout applied to
+// every S3 request the Bkt issues.
+func ConnectTimeout(d time.Duration) func(*Bkt) {
+	return func(b *Bkt) { b.connectTimeout = d }
+}
+
+// RaceWindow overrides how long Delete treats a key as too young to
+// hard-delete: objects younger than this are tagged for deferred
+// removal instead, so that a concurrently-running retry of the same
+// transcription job can't have its input deleted out from under it.
+func RaceWindow(d time.Duration) func(*Bkt) {
+	return func(b *Bkt) { b.raceWindow = d }
+}
+
+// TrashLifetime overrides how long a soft-deleted object (see
+// RaceWindow) is kept around before EmptyTrash reclaims it.
+func TrashLifetime(d time.Duration) func(*Bkt) {
+	return func(b *Bkt) { b.trashLifetime = d }
+}
+
+// UnsafeDelete disables the RaceWindow check, making Delete hard-delete
+// objects immediately regardless of age. Use it for buckets that are
+// known not to be shared across retrying jobs, e.g. in tests.
+func UnsafeDelete(v bool) func(*Bkt) {
+	return func(b *Bkt) { b.unsafeDelete = v }
+}
+
+func (c *Client) NewBkt(name string, opts ...func(*Bkt)) *Bkt {
+	b := &Bkt{
+		name:           name,
+		readTimeout:    defaultReadTimeout,
+		connectTimeout: defaultConnectTimeout,
+		raceWindow:     defaultRaceWindow,
+		trashLifetime:  defaultTrashLifetime,
+	}
+	for _, f := range opts {
+		f(b)
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: b.connectTimeout,
+			}).DialContext,
+			ResponseHeaderTimeout: b.readTimeout,
+		},
 	}
+	b.client = s3.New(c.sess, aws.NewConfig().WithHTTPClient(httpClient))
+	return b
 }
 
-func (b *Bkt) UploadObj(ctx context.Context, r io.Reader, key string) (string, error) {
+func (b *Bkt) Upload(ctx context.Context, key string, r io.Reader) (string, error) {
 	uploader := s3manager.NewUploaderWithClient(b.client)
 	resp, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 		Bucket: aws.String(b.name),
@@ -39,8 +141,29 @@ func (b *Bkt) UploadObj(ctx context.Context, r io.Reader, key string) (string, e
 	return resp.Location, nil
 }
 
-func (b *Bkt) Trash(key string) error {
-	if _, err := b.client.DeleteObject(&s3.DeleteObjectInput{
+// Delete removes key. Unless UnsafeDelete is set, an object younger than
+// RaceWindow is not hard-deleted: it is tagged with an expires-at time
+// of now+TrashLifetime instead, and ErrS3TrashDisabled is returned so
+// callers can tell the two outcomes apart. EmptyTrash later reclaims
+// objects tagged this way once they expire.
+func (b *Bkt) Delete(ctx context.Context, key string) error {
+	if !b.unsafeDelete && b.raceWindow > 0 {
+		head, err := b.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(b.name),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to stat obj before trashing: %w", err)
+		}
+		if age := time.Since(aws.TimeValue(head.LastModified)); age < b.raceWindow {
+			if err := b.tagForTrash(ctx, key); err != nil {
+				return fmt.Errorf("unable to tag obj for deferred trash: %w", err)
+			}
+			return ErrS3TrashDisabled
+		}
+	}
+
+	if _, err := b.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(b.name),
 		Key:    aws.String(key),
 	}); err != nil {
@@ -48,3 +171,79 @@ func (b *Bkt) Trash(key string) error {
 	}
 	return nil
 }
+
+func (b *Bkt) tagForTrash(ctx context.Context, key string) error {
+	expiresAt := time.Now().Add(b.trashLifetime).Format(time.RFC3339)
+	_, err := b.client.PutObjectTaggingWithContext(ctx, &s3.PutObjectTaggingInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+		Tagging: &s3.Tagging{
+			TagSet: []*s3.Tag{
+				{Key: aws.String(trashTagKey), Value: aws.String(expiresAt)},
+			},
+		},
+	})
+	return err
+}
+
+// EmptyTrash scans the bucket for objects previously soft-deleted by
+// Delete (see RaceWindow) and hard-deletes those whose expires-at tag
+// has passed, regardless of UnsafeDelete.
+func (b *Bkt) EmptyTrash(ctx context.Context) error {
+	var firstErr error
+	recordErr := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	err := b.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.name),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, obj := range page.Contents {
+			tagging, err := b.client.GetObjectTaggingWithContext(ctx, &s3.GetObjectTaggingInput{
+				Bucket: aws.String(b.name),
+				Key:    obj.Key,
+			})
+			if err != nil {
+				recordErr(fmt.Errorf("unable to read tags for %s: %w", aws.StringValue(obj.Key), err))
+				continue
+			}
+
+			for _, t := range tagging.TagSet {
+				if aws.StringValue(t.Key) != trashTagKey {
+					continue
+				}
+				expiresAt, err := time.Parse(time.RFC3339, aws.StringValue(t.Value))
+				if err != nil || time.Now().Before(expiresAt) {
+					break
+				}
+				if _, err := b.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+					Bucket: aws.String(b.name),
+					Key:    obj.Key,
+				}); err != nil {
+					recordErr(fmt.Errorf("unable to delete trashed obj %s: %w", aws.StringValue(obj.Key), err))
+				}
+				break
+			}
+		}
+		return true
+	})
+	if err != nil {
+		recordErr(fmt.Errorf("unable to list bkt objects: %w", err))
+	}
+	return firstErr
+}
+
+// SignedURL returns a temporary, pre-signed GET URL for key.
+func (b *Bkt) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	req, _ := b.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	})
+	url, err := req.Presign(ttl)
+	if err != nil {
+		return "", fmt.Errorf("unable to presign s3 obj url: %w", err)
+	}
+	return url, nil
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"git.keepinmind.info/subgensdk/sgenc"
+	"git.keepinmind.info/subgensdk/sgtr/ffmpeg"
+	"git.keepinmind.info/subgensdk/sgtr/tr"
+	"github.com/aws/aws-sdk-go/aws"
+	ts "github.com/aws/aws-sdk-go/service/transcribeservice"
+	tss "github.com/aws/aws-sdk-go/service/transcribestreamingservice"
+)
+
+// streamChunkDuration is the amount of audio pumped into each AWS
+// Transcribe audio event, matching the ~100ms chunking the service
+// expects.
+const streamChunkDuration = 100 * time.Millisecond
+
+// streamChunkBytes is the Linear16, 16kHz, mono byte count that
+// corresponds to streamChunkDuration.
+const streamChunkBytes = int(16000 * 1 * 2 /* channels * bytes/sample */ / 10)
+
+// stream adapts an AWS Transcribe streaming session to tr.TrStreamer. It
+// pumps audio to AWS in one goroutine and turns incoming TranscriptEvents
+// into records in another; unlike the interim/final split AWS exposes
+// through Result.IsPartial, records are delivered on a single Rx channel
+// with StrTrRec.IsFinal set accordingly, matching how the google backend
+// already reports interim results.
+type stream struct {
+	estream *tss.StartStreamTranscriptionEventStream
+
+	rx chan *tr.DiarizedStrTrRec
+
+	mu  sync.Mutex
+	err error
+}
+
+// setErr records err as the stream's terminal error, guarding it against
+// the concurrent writes pumpAudio and listen can both attempt.
+func (s *stream) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// TranscribeStream transcodes req's input to Linear16 and streams it to
+// AWS Transcribe's streaming API, producing partial and final records on
+// tr.TrStreamer.Rx() as they become available.
+func (c *Client) TranscribeStream(ctx context.Context, req *tr.Req, sessionTimeout time.Duration) (tr.TrStreamer, error) {
+	tsc := ts.New(c.sess)
+	bkt, err := c.bucketFor(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare storage bucket: %w", err)
+	}
+
+	// Vocabulary handling is shared with the batch TranscribeFile path.
+	var vname, vkey string
+	if req.HasSpeechContext() {
+		vctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+		defer cancel()
+		if vname, vkey, err = makeVocabulary(vctx, tsc, bkt, req); err != nil {
+			return nil, fmt.Errorf("unable to build vocabulary: %w", err)
+		}
+	}
+
+	t := ffmpeg.New(ffmpeg.FormatL16(), ffmpeg.Input(req.Input))
+	if err := t.Start(); err != nil {
+		return nil, fmt.Errorf("unable to transcode input to linear 16: %w", err)
+	}
+
+	input := &tss.StartStreamTranscriptionInput{
+		LanguageCode:         aws.String(req.Lang),
+		MediaEncoding:        aws.String(tss.MediaEncodingPcm),
+		MediaSampleRateHertz: aws.Int64(16000),
+	}
+	if vname != "" {
+		input.VocabularyName = aws.String(vname)
+	}
+
+	resp, err := tss.New(c.sess).StartStreamTranscriptionWithContext(ctx, input)
+	if err != nil {
+		t.Close()
+		return nil, fmt.Errorf("unable to start transcribe streaming session: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &stream{
+		estream: resp.GetStream(),
+		rx:      make(chan *tr.DiarizedStrTrRec),
+	}
+
+	go s.pumpAudio(ctx, t, cancel)
+	go s.listen(cancel)
+
+	go func() {
+		<-ctx.Done()
+		t.Close()
+		s.estream.Close()
+		if vname != "" {
+			removeVocabulary(tsc, vname)
+		}
+		if vkey != "" {
+			bkt.Delete(context.Background(), vkey)
+		}
+	}()
+
+	return s, nil
+}
+
+// pumpAudio reads Linear16 audio out of src in streamChunkDuration-sized
+// chunks and forwards each one as an AudioEvent, until src is exhausted,
+// the context is canceled or the send itself fails. It calls cancel
+// before returning no matter how it exits, including on a clean EOF from
+// src, so that the teardown goroutine started by TranscribeStream always
+// runs and half-closes estream, letting AWS finalize the session instead
+// of leaving it open until its own idle timeout fires and listen reports
+// that timeout as an error.
+func (s *stream) pumpAudio(ctx context.Context, src io.ReadCloser, cancel context.CancelFunc) {
+	defer cancel()
+	defer src.Close()
+
+	buf := make([]byte, streamChunkBytes)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			event := &tss.AudioEvent{AudioChunk: append([]byte{}, buf[:n]...)}
+			if sendErr := s.estream.Send(ctx, event); sendErr != nil {
+				s.setErr(fmt.Errorf("unable to send audio event: %w", sendErr))
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				s.setErr(fmt.Errorf("unable to read audio input: %w", err))
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+// listen receives TranscriptEvents off the event stream and turns them
+// into records, closing Rx (and calling cancel, so pumpAudio stops too)
+// once the stream ends for any reason.
+func (s *stream) listen(cancel context.CancelFunc) {
+	defer cancel()
+	defer close(s.rx)
+
+	for event := range s.estream.Events() {
+		te, ok := event.(*tss.TranscriptEvent)
+		if !ok || te.Transcript == nil {
+			continue
+		}
+		for _, rr := range te.Transcript.Results {
+			for _, rec := range mapTranscriptResult(rr) {
+				s.rx <- rec
+			}
+		}
+	}
+	if err := s.estream.Err(); err != nil {
+		log.Printf("[INFO] transcribe event stream closed: %v", err)
+		s.setErr(err)
+	}
+}
+
+// mapTranscriptResult converts a streaming Result's most likely
+// alternative into records, keeping r.IsPartial so that callers can tell
+// stabilized (final) chunks from ones still liable to change. AWS
+// Transcribe's streaming API does not support diarization, so every record
+// is reported under the zero-value speaker tag.
+func mapTranscriptResult(r *tss.Result) []*tr.DiarizedStrTrRec {
+	if len(r.Alternatives) == 0 {
+		return nil
+	}
+
+	isFinal := r.IsPartial == nil || !*r.IsPartial
+	alt := r.Alternatives[0]
+	acc := make([]*tr.DiarizedStrTrRec, 0, len(alt.Items))
+	for _, it := range alt.Items {
+		if it.Type == nil || *it.Type != "pronunciation" || it.Content == nil {
+			continue
+		}
+		acc = append(acc, &tr.DiarizedStrTrRec{
+			StrTrRec: &sgenc.StrTrRec{
+				TrRec: &sgenc.TrRec{
+					Start:   durationFromSeconds(it.StartTime),
+					End:     durationFromSeconds(it.EndTime),
+					TextRaw: *it.Content,
+				},
+				IsFinal: isFinal,
+			},
+		})
+	}
+	return acc
+}
+
+func durationFromSeconds(s *float64) time.Duration {
+	if s == nil {
+		return 0
+	}
+	return time.Duration(*s * float64(time.Second))
+}
+
+func (s *stream) Rx() <-chan *tr.DiarizedStrTrRec {
+	return s.rx
+}
+
+func (s *stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"git.keepinmind.info/subgensdk/sgenc"
+	"git.keepinmind.info/subgensdk/sgtr/blob"
 	"git.keepinmind.info/subgensdk/sgtr/ffmpeg"
 	"git.keepinmind.info/subgensdk/sgtr/tr"
 	"github.com/aws/aws-sdk-go/aws"
@@ -194,7 +195,7 @@ type segReq struct {
 	seg   *ffmpeg.Seg
 }
 
-func (c *Client) transcribeSeg(ctx context.Context, tsc *ts.TranscribeService, bkt *Bkt, req *segReq) (*trSeg, error) {
+func (c *Client) transcribeSeg(ctx context.Context, tsc *ts.TranscribeService, bkt blob.Bucket, req *segReq) (*trSeg, error) {
 	// Open the file.
 	file, err := os.Open(req.seg.Name)
 	if err != nil {
@@ -207,11 +208,11 @@ func (c *Client) transcribeSeg(ctx context.Context, tsc *ts.TranscribeService, b
 	key := filepath.Join(req.ID, filen)
 
 	// TODO: Add progress here.
-	uri, err := bkt.UploadObj(ctx, file, key)
+	uri, err := bkt.Upload(ctx, key, file)
 	if err != nil {
 		return nil, fmt.Errorf("unable to start transcription: %w", err)
 	}
-	defer bkt.Trash(key)
+	defer bkt.Delete(ctx, key)
 
 	// Start transcription job.
 	jobid := req.ID + "-" + strconv.Itoa(req.seg.Index)
@@ -293,7 +294,7 @@ func mkvl(w ...string) string {
 // that holds the vocabulary input table file. This function waits until the vocabulary its ready
 // to be used, hence it is pretty slow (roughly 4 minutes!).
 // In case of error, the s3 file and the vocabulary are removed.
-func makeVocabulary(ctx context.Context, tsc *ts.TranscribeService, bkt *Bkt, req *tr.Req) (string, string, error) {
+func makeVocabulary(ctx context.Context, tsc *ts.TranscribeService, bkt blob.Bucket, req *tr.Req) (string, string, error) {
 	// Build Phrases first.
 	sctx, err := req.ReadSpeechContext()
 	if err != nil {
@@ -308,14 +309,14 @@ func makeVocabulary(ctx context.Context, tsc *ts.TranscribeService, bkt *Bkt, re
 		buf.WriteString(mkvl(strings.ReplaceAll(v, " ", "-"), v))
 	}
 	key := filepath.Join(req.ID) + "-vocabulary"
-	loc, err := bkt.UploadObj(ctx, buf, key)
+	loc, err := bkt.Upload(ctx, key, buf)
 	if err != nil {
 		return "", "", fmt.Errorf("unable to upload vocabulary: %w", err)
 	}
 	cleanup := true
 	defer func() {
 		if cleanup {
-			bkt.Trash(key)
+			bkt.Delete(ctx, key)
 		}
 	}()
 
@@ -375,7 +376,7 @@ func removeVocabulary(tsc *ts.TranscribeService, name string) error {
 	return nil
 }
 
-func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.WriteProgressUpdateFunc) ([]*sgenc.TrRec, error) {
+func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.WriteProgressUpdateFunc) ([]*tr.DiarizedTrRec, error) {
 	// Prepare work space.
 	wdp := filepath.Join(os.TempDir(), filepath.Base(os.Args[0]), req.ID)
 	os.MkdirAll(wdp, os.ModePerm)
@@ -405,7 +406,10 @@ func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.Write
 
 	// Open transcribing session.
 	tsc := ts.New(c.sess)
-	bkt := c.NewBkt(req.Bkt)
+	bkt, err := c.bucketFor(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare storage bucket: %w", err)
+	}
 
 	// Build vocabulary if needed.
 	var vname string
@@ -418,7 +422,7 @@ func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.Write
 		}
 		// TODO: watch out, error is silently discarded.
 		defer removeVocabulary(tsc, vname)
-		defer bkt.Trash(key)
+		defer bkt.Delete(ctx, key)
 	}
 
 	// Transcode in parallel, then join the results back.
@@ -460,13 +464,13 @@ func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.Write
 		return trsegs[i].seg.Index < trsegs[j].seg.Index
 	})
 
-	recs := []*sgenc.TrRec{}
+	// AWS Transcribe's batch API does not support diarization, so every
+	// record is reported under the zero-value speaker tag.
+	recs := []*tr.DiarizedTrRec{}
 	for _, v := range trsegs {
-		recs = append(recs, v.makeTrRecs()...)
+		for _, rec := range v.makeTrRecs() {
+			recs = append(recs, &tr.DiarizedTrRec{TrRec: rec})
+		}
 	}
 	return recs, nil
 }
-
-func (c *Client) TranscribeStream(context.Context, *tr.Req, time.Duration) (tr.TrStreamer, error) {
-	return nil, fmt.Errorf("stream is not supported yet with aws engine")
-}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+// Package blob defines the object storage abstraction diroom's
+// transcription backends stage their input (and, where needed,
+// vocabulary) files through, independently of which cloud the files end
+// up living on.
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Bucket is implemented by every object storage backend diroom can use.
+// Transcription engines should depend on this interface rather than on a
+// concrete provider, so that the bucket backing a given engine (e.g. an
+// AWS Transcribe job reading from S3) can be chosen independently of the
+// engine itself.
+type Bucket interface {
+	// Upload streams r to key, returning the resulting object's URI,
+	// whose form depends on the backend (e.g. "gs://bkt/key" for gcs,
+	// the object's https location for s3).
+	Upload(ctx context.Context, key string, r io.Reader) (string, error)
+	// Delete removes key.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a temporary, pre-signed GET URL for key, valid
+	// for ttl.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Config describes a single "storage:" entry of config.yaml: which
+// backend to use and the bucket/container it should talk to. Backend
+// packages interpret the remaining, provider-specific fields themselves.
+type Config struct {
+	// Backend selects the registered Factory to use, e.g. "s3", "gcs",
+	// "oss" or "fs".
+	Backend string `yaml:"backend"`
+	// Bucket is the bucket/container name (or, for the "fs" backend,
+	// the root directory) Upload/Delete/SignedURL operate against.
+	Bucket string `yaml:"bucket"`
+	// Region is used by region-aware backends (s3); ignored by the ones
+	// that are not.
+	Region string `yaml:"region"`
+	// Endpoint overrides the backend's default API endpoint, e.g. an
+	// Aliyun OSS regional endpoint.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// Factory builds a Bucket out of cfg.
+type Factory func(ctx context.Context, cfg Config) (Bucket, error)
+
+var backends = map[string]Factory{}
+
+// Register makes a Bucket backend available under name. Backend packages
+// are expected to call it from an init function, so that importing them
+// for their side effect is enough to make them selectable through New.
+func Register(name string, f Factory) {
+	backends[name] = f
+}
+
+// New returns the Bucket backend registered under cfg.Backend, or an
+// error if none matches.
+func New(ctx context.Context, cfg Config) (Bucket, error) {
+	f, ok := backends[cfg.Backend]
+	if !ok {
+		return nil, fmt.Errorf("unsupported storage backend %s", cfg.Backend)
+	}
+	return f(ctx, cfg)
+}
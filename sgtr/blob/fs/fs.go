@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+// Package fs implements blob.Bucket on top of the local filesystem, so
+// that diroom's pipeline can run without any cloud storage dependency
+// (local development, tests, or an on-prem deployment).
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"git.keepinmind.info/subgensdk/sgtr/blob"
+)
+
+func init() {
+	blob.Register("fs", func(ctx context.Context, cfg blob.Config) (blob.Bucket, error) {
+		return NewBkt(cfg.Bucket)
+	})
+}
+
+// Bkt is a blob.Bucket rooted at a local directory.
+type Bkt struct {
+	root string
+}
+
+var _ blob.Bucket = (*Bkt)(nil)
+
+// NewBkt returns a Bkt rooted at root, creating it if it does not exist
+// yet.
+func NewBkt(root string) (*Bkt, error) {
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to prepare fs bkt root %s: %w", root, err)
+	}
+	return &Bkt{root: root}, nil
+}
+
+func (b *Bkt) path(key string) string {
+	return filepath.Join(b.root, key)
+}
+
+func (b *Bkt) Upload(ctx context.Context, key string, r io.Reader) (string, error) {
+	path := b.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", fmt.Errorf("unable to prepare fs bkt dir for %s: %w", key, err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to create fs bkt obj %s: %w", key, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return "", fmt.Errorf("unable to write fs bkt obj %s: %w", key, err)
+	}
+	return "file://" + path, nil
+}
+
+func (b *Bkt) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(b.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to delete fs bkt obj %s: %w", key, err)
+	}
+	return nil
+}
+
+// SignedURL has no real counterpart on a local filesystem: it returns
+// the same "file://" URI Upload produces, ignoring ttl.
+func (b *Bkt) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return "file://" + b.path(key), nil
+}
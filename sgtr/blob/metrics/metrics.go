@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+// Package metrics wraps a blob.Bucket with a Prometheus-instrumented
+// proxy, following the stats-tracking bucket proxy pattern used by
+// Arvados' keepstore: every operation is counted, its transferred bytes
+// tallied and its latency observed, so operators can see how much a
+// room is hitting object storage with and how slow that storage is.
+package metrics
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"git.keepinmind.info/subgensdk/sgtr/blob"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	opsTotalDesc = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "sgtr",
+		Subsystem: "blob",
+		Name:      "ops_total",
+		Help:      "Number of blob.Bucket operations performed, by op and bucket.",
+	}, []string{"op", "bucket"})
+	bytesTotalDesc = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "sgtr",
+		Subsystem: "blob",
+		Name:      "bytes_total",
+		Help:      "Number of bytes transferred by blob.Bucket.Upload, by op and bucket.",
+	}, []string{"op", "bucket"})
+	opDurationDesc = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "sgtr",
+		Subsystem: "blob",
+		Name:      "op_duration_seconds",
+		Help:      "Latency of blob.Bucket operations, by op and bucket.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"op", "bucket"})
+)
+
+// register registers c with reg, reusing the collector already
+// registered under the same name if Instrument has already been called
+// against reg (e.g. once per bucket/room, against the process-wide
+// registry). It panics on any other registration error, same as
+// prometheus.MustRegister.
+func register(reg prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	if err := reg.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
+}
+
+// proxy wraps a blob.Bucket, instrumenting Upload, Delete and SignedURL.
+type proxy struct {
+	blob.Bucket
+	bucket string
+
+	opsTotal   *prometheus.CounterVec
+	bytesTotal *prometheus.CounterVec
+	opDuration *prometheus.HistogramVec
+}
+
+// Instrument wraps b so that every Upload/Delete/SignedURL call
+// increments ops_total{op,bucket}, adds to bytes_total{op,bucket} (only
+// Upload transfers bytes worth counting) and observes
+// op_duration_seconds{op,bucket}, then registers the collectors with
+// reg. labels are joined with "/" to form the "bucket" label value,
+// letting callers identify e.g. a backend plus room ("s3/room-42").
+func Instrument(b blob.Bucket, reg prometheus.Registerer, labels ...string) blob.Bucket {
+	return &proxy{
+		Bucket:     b,
+		bucket:     strings.Join(labels, "/"),
+		opsTotal:   register(reg, opsTotalDesc).(*prometheus.CounterVec),
+		bytesTotal: register(reg, bytesTotalDesc).(*prometheus.CounterVec),
+		opDuration: register(reg, opDurationDesc).(*prometheus.HistogramVec),
+	}
+}
+
+func (p *proxy) observe(op string, start time.Time) {
+	p.opsTotal.WithLabelValues(op, p.bucket).Inc()
+	p.opDuration.WithLabelValues(op, p.bucket).Observe(time.Since(start).Seconds())
+}
+
+func (p *proxy) Upload(ctx context.Context, key string, r io.Reader) (string, error) {
+	start := time.Now()
+	cr := &countingReader{r: r}
+	uri, err := p.Bucket.Upload(ctx, key, cr)
+	p.bytesTotal.WithLabelValues("upload", p.bucket).Add(float64(cr.n))
+	p.observe("upload", start)
+	return uri, err
+}
+
+func (p *proxy) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := p.Bucket.Delete(ctx, key)
+	p.observe("delete", start)
+	return err
+}
+
+func (p *proxy) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	start := time.Now()
+	url, err := p.Bucket.SignedURL(ctx, key, ttl)
+	p.observe("signed_url", start)
+	return url, err
+}
+
+// countingReader tallies the bytes read through it, so Upload can report
+// how many were actually transferred without requiring callers to know
+// the size of r upfront.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -12,6 +12,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
@@ -20,11 +21,16 @@ import (
 	"git.keepinmind.info/subgensdk/sgenc"
 	"git.keepinmind.info/subgensdk/sgenc/strraw"
 	"git.keepinmind.info/subgensdk/sgenc/trraw"
-	"git.keepinmind.info/subgensdk/sgtr/aws"
-	"git.keepinmind.info/subgensdk/sgtr/google"
+	_ "git.keepinmind.info/subgensdk/sgtr/aws"
+	"git.keepinmind.info/subgensdk/sgtr/blob"
+	_ "git.keepinmind.info/subgensdk/sgtr/blob/fs"
+	_ "git.keepinmind.info/subgensdk/sgtr/google"
+	_ "git.keepinmind.info/subgensdk/sgtr/oss"
 	"git.keepinmind.info/subgensdk/sgtr/tr"
 	"github.com/google/uuid"
 	"github.com/kim-company/pmux/pwrap"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/yaml.v2"
 )
 
 func errorf(format string, args ...interface{}) {
@@ -71,6 +77,24 @@ func makeOnCommandOption(cancel context.CancelFunc) func(*pwrap.UnixCommBridge)
 	})
 }
 
+// loadStorageConfig reads the storage: block of the YAML config file at
+// path, describing the blob.Bucket backend (and its bucket/region/
+// endpoint) transcription engines should stage their input through
+// instead of their own cloud's native bucket.
+func loadStorageConfig(path string) (blob.Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return blob.Config{}, fmt.Errorf("unable to read storage config: %w", err)
+	}
+	var v struct {
+		Storage blob.Config `yaml:"storage"`
+	}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return blob.Config{}, fmt.Errorf("unable to parse storage config: %w", err)
+	}
+	return v.Storage, nil
+}
+
 func setLogOutput(path string) error {
 	switch path {
 	case "", "/dev/null", "null", "discard":
@@ -89,22 +113,6 @@ func setLogOutput(path string) error {
 	}
 }
 
-type Transcriber interface {
-	TranscribeFile(context.Context, *tr.Req, pwrap.WriteProgressUpdateFunc) ([]*sgenc.TrRec, error)
-	TranscribeStream(context.Context, *tr.Req, time.Duration) (tr.TrStreamer, error)
-}
-
-func newTranscriber(ctx context.Context, engine, region string) (Transcriber, error) {
-	switch engine {
-	case "google":
-		return google.NewClient(ctx), nil
-	case "aws":
-		return aws.NewClient(region)
-	default:
-		return nil, fmt.Errorf("unsupported transcription engine %s", engine)
-	}
-}
-
 func main() {
 	in := flag.String("in", "-", "Input file path. Use - for stdin.")
 	lang := flag.String("lang", "en-US", "Expected input spoken language code, formatted as a BCP-47 identifier (RFC5646).")
@@ -112,12 +120,14 @@ func main() {
 	id := flag.String("id", uuid.New().String(), "Identifier for this transcription task. It is also used as reference when storing data.")
 	sp := flag.String("sp", "", "Path to the unix socket file. Use - to print progress to stdout.")
 	lp := flag.String("lp", "", "Log file path. Use - for stderr.")
-	e := flag.String("e", "google", "Transcription engine to use. Choose either aws or google and bkt accordingly.")
+	e := flag.String("e", "google", "Transcription engine to use. Choose google or aws, and bkt accordingly.")
 	r := flag.String("r", "eu-west-1", "AWS region. Used only if engine is aws, ignored otherwise.")
 	c := flag.String("c", "", "Context file path. Provide a list of phrases/words that the audio is supposed to contain for improved recognition.")
 	s := flag.Bool("s", false, "Enable streaming mode. The input is expected to be an audio stream, useful with microphones and audio live streaming in general.")
 	i := flag.Int("i", 15, "Session interval duration, expressed in seconds.")
 	interim := flag.Bool("interim", false, "Produce also intermediate results. Applied only in streaming mode.")
+	storageConf := flag.String("storage-conf", "", "Path to a YAML config file with a storage: block, overriding the engine's native bucket with a blob.Bucket backend of choice (s3, gcs, oss or fs).")
+	metricsAddr := flag.String("metrics-addr", "", "Address to serve Prometheus /metrics on, e.g. :9090. Left empty, no metrics server is started.")
 	flag.Parse()
 
 	// Configure log.
@@ -126,26 +136,47 @@ func main() {
 		os.Exit(-1)
 	}
 
+	// Serve storage usage metrics, gathered regardless of -metrics-addr
+	// by every blob.Bucket the engines acquire.
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				errorf("metrics server exited: %v", err)
+			}
+		}()
+	}
+
 	// Setup transcribe engine.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	var err error
-	var eng Transcriber
-	if eng, err = newTranscriber(ctx, *e, *r); err != nil {
+	var eng tr.Transcriber
+	if eng, err = tr.New(ctx, *e, *r); err != nil {
 		errorf("unable to initiate transcript engine: %v", err)
 		os.Exit(-1)
 	}
 
 	// Build transcribe request.
-	req := tr.NewReq(
+	reqOpts := []func(*tr.Req){
 		tr.Input(*in),
 		tr.Language(*lang),
 		tr.Bucket(*bkt),
 		tr.ID(*id),
 		tr.SpeechContext(*c),
 		tr.Interim(*interim),
-	)
+	}
+	if *storageConf != "" {
+		scfg, err := loadStorageConfig(*storageConf)
+		if err != nil {
+			errorf(err.Error())
+			os.Exit(-1)
+		}
+		reqOpts = append(reqOpts, tr.Storage(scfg))
+	}
+	req := tr.NewReq(reqOpts...)
 
 	// Handle signals.
 	sigch := make(chan os.Signal, 1)
@@ -155,35 +186,58 @@ func main() {
 		cancel()
 	}()
 
+	// Prepare progress writer, used to report per-segment progress in
+	// streaming mode and the usual transcoding/upload/poll progress in
+	// file mode.
+	f, pcancel, err := makeProgressWriter(ctx, *sp)
+	if err != nil {
+		errorf(err.Error())
+		os.Exit(-1)
+	}
+	defer pcancel()
+
 	if *s {
 		interval := time.Second * time.Duration(*i)
-		transcribeStream(ctx, eng, req, os.Stdout, interval)
+		transcribeStream(ctx, eng, req, os.Stdout, interval, f)
 	} else {
-		// Prepare progress writer.
-		f, cancel, err := makeProgressWriter(ctx, *sp)
-		if err != nil {
-			errorf(err.Error())
-			os.Exit(-1)
-		}
-		defer cancel()
-
 		transcribeFile(ctx, eng, req, os.Stdout, f)
 	}
 }
 
-func transcribeStream(ctx context.Context, eng Transcriber, req *tr.Req, out io.Writer, interval time.Duration) {
+// transcribeStream drives a streaming transcription session, grouping its
+// records into interval-sized segments through tr.AudioSegmentStream and
+// publishing one progress update per segment through pf, the same way
+// transcribeFile already does for the whole-file path.
+func transcribeStream(ctx context.Context, eng tr.Transcriber, req *tr.Req, out io.Writer, interval time.Duration, pf pwrap.WriteProgressUpdateFunc) {
 	w := strraw.NewWriter(out)
 	stream, err := eng.TranscribeStream(ctx, req, interval)
 	if err != nil {
 		errorf(err.Error())
 		os.Exit(-1)
 	}
-	for rec := range stream.Rx() {
-		if err := w.Write(rec); err != nil {
-			errorf("unable to write record: %v", err)
-			os.Exit(-1)
+
+	segs := tr.NewAudioSegmentStream(stream, interval)
+	for {
+		sp, err := segs.Next(ctx)
+		if sp.Segment != nil {
+			for _, rec := range sp.Segment.Records {
+				if wErr := w.Write(rec.StrTrRec); wErr != nil {
+					errorf("unable to write record: %v", wErr)
+					os.Exit(-1)
+				}
+			}
+			w.Flush()
+			if pErr := pf(fmt.Sprintf("segment %s", sp.Segment.ID), sp.Stage, sp.Stages, len(sp.Segment.Records), len(sp.Segment.Records)); pErr != nil {
+				log.Printf("[ERROR] unable to publish progress update: %v", pErr)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				errorf("stream exited with error: %v", err)
+				os.Exit(-1)
+			}
+			break
 		}
-		w.Flush()
 	}
 
 	if err := stream.Err(); err != nil {
@@ -192,18 +246,24 @@ func transcribeStream(ctx context.Context, eng Transcriber, req *tr.Req, out io.
 	}
 }
 
-func transcribeFile(ctx context.Context, eng Transcriber, req *tr.Req, out io.Writer, f pwrap.WriteProgressUpdateFunc) {
+func transcribeFile(ctx context.Context, eng tr.Transcriber, req *tr.Req, out io.Writer, f pwrap.WriteProgressUpdateFunc) {
 	// This might take a while.
-	var records []*sgenc.TrRec
+	var records []*tr.DiarizedTrRec
 	var err error
 	if records, err = eng.TranscribeFile(ctx, req, f); err != nil {
 		errorf(err.Error())
 		os.Exit(-1)
 	}
 
-	// Encode results.
+	// Encode results. trraw has no room for SpeakerTag (see
+	// tr.DiarizedTrRec), so it is dropped here; rpc.Service.recordToPB
+	// drops it the same way.
+	trrecs := make([]*sgenc.TrRec, len(records))
+	for i, r := range records {
+		trrecs[i] = r.TrRec
+	}
 	w := trraw.NewWriter(out)
-	if err := w.WriteAll(records); err != nil {
+	if err := w.WriteAll(trrecs); err != nil {
 		errorf(err.Error())
 		os.Exit(-1)
 	}
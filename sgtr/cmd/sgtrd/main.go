@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+// Command sgtrd exposes a tr.Transcriber backend over gRPC, so that
+// sgtr's CLI (or any other client generated from rpc/transcription.proto)
+// can submit and watch transcription jobs against a long-running process
+// instead of a one-shot subprocess per request.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	_ "git.keepinmind.info/subgensdk/sgtr/aws"
+	_ "git.keepinmind.info/subgensdk/sgtr/google"
+	"git.keepinmind.info/subgensdk/sgtr/rpc"
+	"git.keepinmind.info/subgensdk/sgtr/rpc/rpcpb"
+	"git.keepinmind.info/subgensdk/sgtr/tr"
+	"google.golang.org/grpc"
+)
+
+func errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, os.Args[0]+" error: "+format+"\n", args...)
+}
+
+func main() {
+	addr := flag.String("addr", ":8443", "Address to serve the TranscriptionService gRPC API on.")
+	e := flag.String("e", "google", "Transcription engine to use. Choose google or aws.")
+	r := flag.String("r", "eu-west-1", "AWS region. Used only if engine is aws, ignored otherwise.")
+	flag.Parse()
+
+	ctx := context.Background()
+	eng, err := tr.New(ctx, *e, *r)
+	if err != nil {
+		errorf("unable to initiate transcript engine: %v", err)
+		os.Exit(-1)
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		errorf("unable to listen on %s: %v", *addr, err)
+		os.Exit(-1)
+	}
+
+	srv := grpc.NewServer()
+	rpcpb.RegisterTranscriptionServiceServer(srv, rpc.NewService(eng, *r))
+
+	fmt.Fprintf(os.Stderr, "%s: serving TranscriptionService on %s\n", os.Args[0], *addr)
+	if err := srv.Serve(lis); err != nil {
+		errorf("serve exited: %v", err)
+		os.Exit(-1)
+	}
+}
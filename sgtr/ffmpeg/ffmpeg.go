@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,22 +22,39 @@ import (
 
 // Transcoder is a pcm_s16le audio transcoder.
 type Transcoder struct {
-	format string
-	ext    string
-	wd     string // work directory path.
-	segd   int
-	segn   string
-	in     string
-	out    string
-	args   []string
+	format   string
+	ext      string
+	acodec   string
+	bitrate  string
+	wd       string // work directory path.
+	segd     int
+	segn     string
+	in       string
+	out      string
+	args     []string
+	tee      *teeOutput
+	channels int
 
 	stdoutPipe io.ReadCloser
 }
 
+// Channels tells the transcoder how many audio channels the recognizer
+// expects. With n == 1, the default, New forces a downmix to mono; with
+// any other value New passes the input's channels through untouched (no
+// -ac flag), so that multi-channel input (e.g. separate speaker tracks)
+// reaches the recognizer intact for per-channel recognition. The output
+// is not remixed to exactly n channels.
+func Channels(n int) func(*Transcoder) {
+	return func(t *Transcoder) {
+		t.channels = n
+	}
+}
+
 func FormatL16() func(*Transcoder) {
 	return func(t *Transcoder) {
 		t.format = "s16le"
 		t.ext = ".raw"
+		t.acodec = "pcm_s16le"
 	}
 }
 
@@ -44,6 +62,62 @@ func FormatWav() func(*Transcoder) {
 	return func(t *Transcoder) {
 		t.format = "wav"
 		t.ext = ".wav"
+		t.acodec = "pcm_s16le"
+	}
+}
+
+// FormatMP3 configures the transcoder to produce MP3 output, encoded at
+// bitrate (e.g. "128k"). Useful to keep an archival copy alongside a
+// Linear16 stream sent off for recognition; see TeeOutput.
+func FormatMP3(bitrate string) func(*Transcoder) {
+	return func(t *Transcoder) {
+		t.format = "mp3"
+		t.ext = ".mp3"
+		t.acodec = "libmp3lame"
+		t.bitrate = bitrate
+	}
+}
+
+// FormatOpus configures the transcoder to produce Opus output (in an Ogg
+// container), encoded at bitrate (e.g. "96k").
+func FormatOpus(bitrate string) func(*Transcoder) {
+	return func(t *Transcoder) {
+		t.format = "ogg"
+		t.ext = ".opus"
+		t.acodec = "libopus"
+		t.bitrate = bitrate
+	}
+}
+
+// teeOutput describes a second, synchronized output that New adds to the
+// ffmpeg command line via a second `-map 0:a` stanza, so that a single
+// ffmpeg process can fan out e.g. Linear16 (for recognition) and a
+// compressed archival copy at the same time.
+type teeOutput struct {
+	format  string
+	ext     string
+	acodec  string
+	bitrate string
+	path    string
+}
+
+// TeeOutput instructs the transcoder to additionally emit path, encoded
+// according to opts (one of the Format* options), alongside its primary
+// output. Both outputs are produced by the same ffmpeg process, from the
+// same input stream, so they stay in sync.
+func TeeOutput(path string, opts ...func(*Transcoder)) func(*Transcoder) {
+	return func(t *Transcoder) {
+		tt := &Transcoder{}
+		for _, f := range opts {
+			f(tt)
+		}
+		t.tee = &teeOutput{
+			format:  tt.format,
+			ext:     tt.ext,
+			acodec:  tt.acodec,
+			bitrate: tt.bitrate,
+			path:    path,
+		}
 	}
 }
 
@@ -70,24 +144,32 @@ func Wd(path string) func(*Transcoder) {
 
 func New(opts ...func(*Transcoder)) *Transcoder {
 	t := &Transcoder{
-		format: "s16le",
-		ext:    ".raw",
-		wd:     ".",
-		in:     "-",
-		out:    "-",
+		format:   "s16le",
+		ext:      ".raw",
+		acodec:   "pcm_s16le",
+		wd:       ".",
+		in:       "-",
+		out:      "-",
+		channels: 1,
 	}
 	for _, f := range opts {
 		f(t)
 	}
 
-	args := []string{
-		"-i", t.in,
-		"-f", t.format,
-		"-acodec", "pcm_s16le",
-		"-vn",
-		"-ac", "1",
-		"-ar", "16k",
+	args := []string{"-i", t.in, "-vn"}
+	if t.channels == 1 {
+		// Multi-channel inputs are passed through untouched, so that
+		// per-channel recognition (see tr.Req.Channels) can tell the
+		// channels apart; only the common mono case is forced here.
+		args = append(args, "-ac", "1")
+	}
+	args = append(args, "-ar", "16k")
+	if t.tee != nil {
+		// Both outputs read from the same decoded input, so each needs
+		// its own explicit map.
+		args = append(args, "-map", "0:a")
 	}
+	args = append(args, t.outputArgs(t.format, t.acodec, t.bitrate)...)
 	if t.segd > 0 {
 		args = append(args, []string{
 			"-segment_time", strconv.Itoa(t.segd),
@@ -95,11 +177,41 @@ func New(opts ...func(*Transcoder)) *Transcoder {
 		}...)
 	}
 	args = append(args, t.outputName())
+
+	if t.tee != nil {
+		args = append(args, "-map", "0:a")
+		if t.segd > 0 {
+			// -segment_time only takes effect when the output's own muxer
+			// is "segment"; naming a tee.format like "mp3" here (as the
+			// non-segment branch does) makes ffmpeg silently ignore
+			// -segment_time and write one unchunked file instead. The
+			// real output format moves to -segment_format, and this
+			// output needs its own -segment_list since it isn't sharing
+			// the primary output's segment boundaries file.
+			args = append(args, t.outputArgs("segment", t.tee.acodec, t.tee.bitrate)...)
+			args = append(args, "-segment_format", t.tee.format)
+			args = append(args, "-segment_time", strconv.Itoa(t.segd))
+			args = append(args, "-segment_list", t.teeSegmentListName())
+		} else {
+			args = append(args, t.outputArgs(t.tee.format, t.tee.acodec, t.tee.bitrate)...)
+		}
+		args = append(args, t.teeOutputName())
+	}
 	t.args = args
 
 	return t
 }
 
+// outputArgs returns the `-f`/`-acodec`(/`-b:a`) flags for a single output
+// stanza.
+func (t *Transcoder) outputArgs(format, acodec, bitrate string) []string {
+	args := []string{"-f", format, "-acodec", acodec}
+	if bitrate != "" {
+		args = append(args, "-b:a", bitrate)
+	}
+	return args
+}
+
 func (t *Transcoder) Start() error {
 	if t.args == nil || len(t.args) == 0 {
 		return fmt.Errorf("transcoder has not been properly initialized")
@@ -149,6 +261,36 @@ func (t *Transcoder) segmentListName() string {
 	return n + ".csv"
 }
 
+// teeSegmentListName returns the path of the tee output's own segment
+// boundaries file, distinct from segmentListName's so the two outputs'
+// -segment_list writes don't collide.
+func (t *Transcoder) teeSegmentListName() string {
+	n := "segment-tee"
+	if t.wd != "" {
+		n = filepath.Join(t.wd, n)
+	}
+
+	return n + ".csv"
+}
+
+// teeOutputName returns the name of the secondary output configured via
+// TeeOutput. In Segment mode it shares the primary output's "segment"
+// basename and numbering, so that records produced by the two outputs
+// of a given index (and hence timing, taken from the primary segment
+// list) line up; outside of Segment mode it is simply the path given to
+// TeeOutput.
+func (t *Transcoder) teeOutputName() string {
+	if t.segd == 0 {
+		return t.tee.path
+	}
+
+	n := "segment"
+	if t.wd != "" {
+		n = filepath.Join(t.wd, n)
+	}
+	return n + "%02d" + t.tee.ext
+}
+
 func (t *Transcoder) outputName() string {
 	if t.segd == 0 {
 		return "-"
@@ -184,6 +326,20 @@ func (t *Transcoder) GetSegmentList() ([]*Seg, error) {
 	return parseSegmentList(segf, dir)
 }
 
+// TeeSegmentName returns the path of the tee (archival) segment that
+// corresponds to seg, as produced by a Segment-mode transcoder configured
+// with TeeOutput: the two outputs share the same "segment"+index naming
+// and segment_time boundaries, only the extension differs, so seg's entry
+// in the primary segment list (returned by GetSegmentList) also describes
+// the timing of its tee counterpart.
+func (t *Transcoder) TeeSegmentName(seg *Seg) string {
+	if t.tee == nil {
+		return ""
+	}
+	ext := filepath.Ext(seg.Name)
+	return strings.TrimSuffix(seg.Name, ext) + t.tee.ext
+}
+
 func parseSegmentList(src io.Reader, segDir string) ([]*Seg, error) {
 	r := csv.NewReader(src)
 	r.FieldsPerRecord = 3
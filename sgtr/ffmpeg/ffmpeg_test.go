@@ -43,6 +43,37 @@ output010.wav,300.000812,328.864250`
 	}
 }
 
+func TestNewTeeOutput(t *testing.T) {
+	tc := New(Input("in.wav"), FormatL16(), TeeOutput("archive.mp3", FormatMP3("128k")))
+
+	got := strings.Join(tc.args, " ")
+	want := "-i in.wav -vn -ac 1 -ar 16k -map 0:a -f s16le -acodec pcm_s16le - -map 0:a -f mp3 -acodec libmp3lame -b:a 128k archive.mp3"
+	if got != want {
+		t.Fatalf("unexpected ffmpeg args:\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestNewSegmentTeeOutput(t *testing.T) {
+	tc := New(Input("in.wav"), FormatL16(), Segment(30*time.Second), Wd("wd"), TeeOutput("archive.mp3", FormatMP3("128k")))
+
+	got := strings.Join(tc.args, " ")
+	want := "-i in.wav -vn -ac 1 -ar 16k -map 0:a -f segment -acodec pcm_s16le -segment_time 30 -segment_list wd/segment.csv wd/segment%02d.raw" +
+		" -map 0:a -f segment -acodec libmp3lame -b:a 128k -segment_format mp3 -segment_time 30 -segment_list wd/segment-tee.csv wd/segment%02d.mp3"
+	if got != want {
+		t.Fatalf("unexpected ffmpeg args:\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestNewMultiChannel(t *testing.T) {
+	tc := New(Input("in.wav"), FormatL16(), Channels(2))
+
+	got := strings.Join(tc.args, " ")
+	want := "-i in.wav -vn -ar 16k -f s16le -acodec pcm_s16le -"
+	if got != want {
+		t.Fatalf("unexpected ffmpeg args:\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
 func mustParseDuration(t *testing.T, draw string) time.Duration {
 	d, err := time.ParseDuration(draw)
 	if err != nil {
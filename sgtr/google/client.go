@@ -8,9 +8,19 @@ package google
 import (
 	"context"
 
+	"git.keepinmind.info/subgensdk/sgtr/blob"
+	"git.keepinmind.info/subgensdk/sgtr/blob/metrics"
+	"git.keepinmind.info/subgensdk/sgtr/tr"
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/api/option"
 )
 
+func init() {
+	tr.Register("google", func(ctx context.Context, region string) (tr.Transcriber, error) {
+		return NewClient(ctx), nil
+	})
+}
+
 // Client is responsible for retriving the credentials and using them to
 // authenticate with the Google services.
 type Client struct {
@@ -27,3 +37,23 @@ func NewClient(ctx context.Context, opts ...func(*Client)) *Client {
 	}
 	return c
 }
+
+// bucketFor resolves the blob.Bucket req's transcription job should stage
+// its input through: req.Storage's backend when set, falling back to
+// this client's own GCS bucket otherwise. The returned bucket is always
+// wrapped with metrics.Instrument, so storage usage is tracked
+// regardless of which backend ends up serving the room.
+func (c *Client) bucketFor(ctx context.Context, req *tr.Req) (blob.Bucket, error) {
+	if req.Storage.Backend != "" {
+		b, err := blob.New(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		return metrics.Instrument(b, prometheus.DefaultRegisterer, req.Storage.Backend, req.Storage.Bucket), nil
+	}
+	b, err := c.NewBkt(ctx, req.Bkt)
+	if err != nil {
+		return nil, err
+	}
+	return metrics.Instrument(b, prometheus.DefaultRegisterer, "gcs", req.Bkt), nil
+}
@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package google
+
+import (
+	"git.keepinmind.info/subgensdk/sgtr/tr"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
+)
+
+// diarizationConfigV1 builds the v1 SpeakerDiarizationConfig for req, or
+// nil when req did not opt into diarization.
+func diarizationConfigV1(req *tr.Req) *speechpb.SpeakerDiarizationConfig {
+	if !req.HasDiarization() {
+		return nil
+	}
+	return &speechpb.SpeakerDiarizationConfig{
+		EnableSpeakerDiarization: true,
+		MinSpeakerCount:          int32(req.Diarization.MinSpeakers),
+		MaxSpeakerCount:          int32(req.Diarization.MaxSpeakers),
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"git.keepinmind.info/subgensdk/sgenc"
 	"git.keepinmind.info/subgensdk/sgtr/ffmpeg"
 	"git.keepinmind.info/subgensdk/sgtr/tr"
+	"git.keepinmind.info/subgensdk/sgtr/tr/internal"
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/kim-company/pmux/pwrap"
 	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v1"
@@ -56,22 +57,28 @@ func mapDuration(d *duration.Duration) time.Duration {
 	return time.Duration(ns)
 }
 
-func mapSpeechResults(alts []*speechpb.SpeechRecognitionAlternative) []*sgenc.TrRec {
+// mapSpeechResults maps alts into transcript records, attaching the speaker
+// each word was attributed to (populated only when the request enabled
+// diarization; see tr.Req.Diarization).
+func mapSpeechResults(alts []*speechpb.SpeechRecognitionAlternative) []*tr.DiarizedTrRec {
 	if len(alts) == 0 {
-		return []*sgenc.TrRec{}
+		return []*tr.DiarizedTrRec{}
 	}
 
 	// First alternative is the most probable one.
 	alt := alts[0]
-	acc := make([]*sgenc.TrRec, 0, len(alt.Words))
+	acc := make([]*tr.DiarizedTrRec, 0, len(alt.Words))
 	for _, v := range alt.Words {
 		if v.Word == "" {
 			continue
 		}
-		acc = append(acc, &sgenc.TrRec{
-			Start:   mapDuration(v.StartTime),
-			End:     mapDuration(v.EndTime),
-			TextRaw: v.Word,
+		acc = append(acc, &tr.DiarizedTrRec{
+			TrRec: &sgenc.TrRec{
+				Start:   mapDuration(v.StartTime),
+				End:     mapDuration(v.EndTime),
+				TextRaw: v.Word,
+			},
+			SpeakerTag: int(v.SpeakerTag),
 		})
 	}
 	return acc
@@ -109,20 +116,20 @@ func openProgressLoop(ctx context.Context, task *speech.LongRunningRecognizeOper
 // of transcript raw records. Input path is taken from `req`, and it does not
 // matter in which encoding format the file is saved in: this function takes
 // care of transcoding the audio first.
-func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.WriteProgressUpdateFunc) ([]*sgenc.TrRec, error) {
-	// Prepare s3 object writer.
-	bkt, err := c.NewBkt(ctx, req.Bkt)
+func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.WriteProgressUpdateFunc) ([]*tr.DiarizedTrRec, error) {
+	if req.HasRecognizer() {
+		return c.transcribeFileV2(ctx, req, pf)
+	}
+
+	// Prepare the storage bucket.
+	bkt, err := c.bucketFor(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	obj := bkt.Object(req.ID)
-	defer obj.Trash()
-
-	objw := obj.NewWriter(ctx)
-	defer objw.Close()
+	defer bkt.Delete(ctx, req.ID)
 
 	// Input needs to be transcoded to Linear16 first.
-	t := ffmpeg.New(ffmpeg.FormatL16(), ffmpeg.Input(req.Input))
+	t := ffmpeg.New(ffmpeg.FormatL16(), ffmpeg.Input(req.Input), ffmpeg.Channels(req.Channels))
 	if err := t.Start(); err != nil {
 		return nil, fmt.Errorf("unable to transcode input to linear 16: %w", err)
 	}
@@ -130,12 +137,10 @@ func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.Write
 
 	// Upload.
 	pf("uploading", 1, 2, 0, 1)
-	_, err = io.Copy(objw, t)
+	uri, err := bkt.Upload(ctx, req.ID, t)
 	if err != nil {
 		return nil, fmt.Errorf("unable to upload input to google storage: %w", err)
 	}
-
-	objw.Close()
 	pf("uploading", 1, 2, 1, 1)
 
 	// Build speech context.
@@ -151,11 +156,14 @@ func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.Write
 	}
 	task, err := gsc.LongRunningRecognize(ctx, &speechpb.LongRunningRecognizeRequest{
 		Config: &speechpb.RecognitionConfig{
-			Encoding:                   speechpb.RecognitionConfig_LINEAR16,
-			SampleRateHertz:            16000,
-			LanguageCode:               req.Lang,
-			EnableWordTimeOffsets:      true,
-			EnableAutomaticPunctuation: true,
+			Encoding:                            speechpb.RecognitionConfig_LINEAR16,
+			SampleRateHertz:                     16000,
+			AudioChannelCount:                   int32(req.Channels),
+			EnableSeparateRecognitionPerChannel: req.Channels > 1,
+			LanguageCode:                        req.Lang,
+			EnableWordTimeOffsets:               true,
+			EnableAutomaticPunctuation:          true,
+			DiarizationConfig:                   diarizationConfigV1(req),
 			SpeechContexts: []*speechpb.SpeechContext{
 				&speechpb.SpeechContext{
 					Phrases: sctx,
@@ -163,7 +171,7 @@ func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.Write
 			},
 		},
 		Audio: &speechpb.RecognitionAudio{
-			AudioSource: &speechpb.RecognitionAudio_Uri{Uri: obj.URI()},
+			AudioSource: &speechpb.RecognitionAudio_Uri{Uri: uri},
 		},
 	})
 	if err != nil {
@@ -181,7 +189,7 @@ func (c *Client) TranscribeFile(ctx context.Context, req *tr.Req, pf pwrap.Write
 	}
 
 	// Map results to trascript records.
-	records := []*sgenc.TrRec{}
+	records := []*tr.DiarizedTrRec{}
 	for _, v := range resp.Results {
 		records = append(records, mapSpeechResults(v.Alternatives)...)
 	}
@@ -208,9 +216,20 @@ type stream struct {
 	lang           string
 	interim        bool
 
-	rx     chan *sgenc.StrTrRec
-	tx     chan []byte
-	client *speech.Client
+	// vadThreshold and vadHangover configure the energy-based VAD gate
+	// used to tell speech from silence (see vadGate). resetWindow is how
+	// long before sessionTimeout elapses we start looking for a silence
+	// run to reset the session on, instead of waiting for the hard
+	// timeout and risking cutting a sentence mid-word.
+	vadThreshold float64
+	vadHangover  time.Duration
+	resetWindow  time.Duration
+
+	rx chan *tr.DiarizedStrTrRec
+	tx chan []byte
+	// newSession opens a new streaming session against whichever
+	// Speech-to-Text API version the caller selected (v1 or v2).
+	newSession func(ctx context.Context) (sessStream, error)
 
 	timeshiftOffset time.Duration
 }
@@ -220,7 +239,7 @@ type stream struct {
 // longer be used, either because its context was canceled or
 // becuase a fatal error occurred.
 // Has to be called after `Open`.
-func (s *stream) Rx() <-chan *sgenc.StrTrRec {
+func (s *stream) Rx() <-chan *tr.DiarizedStrTrRec {
 	return s.rx
 }
 
@@ -237,7 +256,7 @@ func (s *stream) Err() error {
 // After the stream has been opened, `Rx` will be ready to produce
 // data.
 func (s *stream) Open(ctx context.Context) {
-	s.rx = make(chan *sgenc.StrTrRec)
+	s.rx = make(chan *tr.DiarizedStrTrRec)
 	s.tx = make(chan []byte, txBuffSize)
 	go s.openSessionLoop(ctx)
 }
@@ -270,23 +289,6 @@ func (s *stream) openSessionLoop(ctx context.Context) {
 	}
 }
 
-func computeTimeshiftOffset(bytesSent int) time.Duration {
-	var byteRate float64 = float64(inBitrate) / 8
-	if byteRate == 0 {
-		return 0
-	}
-	var secs float64 = float64(bytesSent) / byteRate
-	if secs == 0 {
-		return 0
-	}
-	durationString := fmt.Sprintf("%fs", secs)
-	d, err := time.ParseDuration(durationString)
-	if err != nil {
-		return 0
-	}
-	return d
-}
-
 type fatalError struct {
 	Err error
 }
@@ -305,24 +307,33 @@ func (s *stream) sessLoop(ctx context.Context) error {
 	if err != nil {
 		return &fatalError{err}
 	}
+	sessStart := time.Now()
 	defer func() {
-		s.timeshiftOffset += computeTimeshiftOffset(sess.bytesSent)
+		// Use the audio actually sent, not wall-clock session age: the vad
+		// gate below skips sending confirmed silence, so a wall-clock
+		// elapsed would overcount the offset by however long the session
+		// spent gated.
+		elapsed := internal.TimeshiftOffset(sess.bytesSent, inBitrate)
+		s.timeshiftOffset += elapsed
 		log.Printf("[INFO] bytes transferred during session: %v", sess.bytesSent)
 		log.Printf("[INFO] new timeshift offset: %v", s.timeshiftOffset)
 	}()
 
 	// There is a transcoding limit of 305 seconds per session.
-	// Restart the session either if we hit the limit in terms of audio duration
-	// or time elapsed.
+	// Restart the session either if we hit the limit in terms of time
+	// elapsed, or earlier, as soon as we find a silence run to reset on
+	// (see gate below).
 	_ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
 	defer cancel()
 
+	gate := &vadGate{Threshold: s.vadThreshold, Hangover: s.vadHangover}
+
 	for {
 		select {
 		case <-_ctx.Done():
 			log.Printf("[INFO] closing transcript session after: %v", _ctx.Err())
 			// Time to reset the session.
-			if err := sess.sstream.CloseSend(); err != nil {
+			if err := sess.sstream.closeSend(); err != nil {
 				return &fatalError{fmt.Errorf("unable to close speech-to-text session: %w", err)}
 			}
 
@@ -345,44 +356,56 @@ func (s *stream) sessLoop(ctx context.Context) error {
 			if !ok {
 				return fmt.Errorf("session tx was closed: %w", sess.err)
 			}
+
+			if speaking := gate.Classify(p); !speaking {
+				if time.Since(sessStart) >= s.sessionTimeout-s.resetWindow {
+					// We're close to the session ceiling and currently in
+					// a confirmed silence run: reset now rather than
+					// waiting for the hard timeout and risking cutting
+					// the next sentence mid-word.
+					cancel()
+				}
+				// Confirmed silence: do not forward it to the backend,
+				// so idle streams do not accumulate billed audio time.
+				continue
+			}
+
 			log.Printf("[INFO] sending audio buffer (size %d) to google --->", len(p))
 			if err := sess.sendAudio(p); err != nil {
 				return err
 			}
-			toff := computeTimeshiftOffset(sess.bytesSent)
-			if toff >= s.sessionTimeout {
-				// We've sent enough audio data for this session!
-				cancel()
-			}
 		}
 	}
 }
 
-func (s *stream) txRecognitionResults(rr ...*sgenc.StrTrRec) {
+func (s *stream) txRecognitionResults(rr ...*tr.DiarizedStrTrRec) {
 	for _, v := range rr {
-		v.ShiftTime(s.timeshiftOffset)
+		v.StrTrRec.ShiftTime(s.timeshiftOffset)
 		s.rx <- v
 	}
 }
 
-func sendConfig(stream speechpb.Speech_StreamingRecognizeClient, lang string, context []string, interim bool) error {
+func sendConfig(stream speechpb.Speech_StreamingRecognizeClient, req *tr.Req) error {
 	speechContexts := []*speechpb.SpeechContext{
 		&speechpb.SpeechContext{
-			Phrases: context,
+			Phrases: nil,
 		},
 	}
 	if err := stream.Send(&speechpb.StreamingRecognizeRequest{
 		StreamingRequest: &speechpb.StreamingRecognizeRequest_StreamingConfig{
 			StreamingConfig: &speechpb.StreamingRecognitionConfig{
 				Config: &speechpb.RecognitionConfig{
-					Encoding:                   speechpb.RecognitionConfig_LINEAR16,
-					SampleRateHertz:            int32(inSampleRate),
-					LanguageCode:               lang,
-					EnableWordTimeOffsets:      true,
-					EnableAutomaticPunctuation: true,
-					SpeechContexts:             speechContexts,
+					Encoding:                            speechpb.RecognitionConfig_LINEAR16,
+					SampleRateHertz:                     int32(inSampleRate),
+					AudioChannelCount:                   int32(req.Channels),
+					EnableSeparateRecognitionPerChannel: req.Channels > 1,
+					LanguageCode:                        req.Lang,
+					EnableWordTimeOffsets:               true,
+					EnableAutomaticPunctuation:          true,
+					DiarizationConfig:                   diarizationConfigV1(req),
+					SpeechContexts:                      speechContexts,
 				},
-				InterimResults: interim,
+				InterimResults: req.Interim,
 			},
 		},
 	}); err != nil {
@@ -391,18 +414,66 @@ func sendConfig(stream speechpb.Speech_StreamingRecognizeClient, lang string, co
 	return nil
 }
 
-func (s *stream) openTrSession(ctx context.Context) (*trSess, error) {
-	stream, err := s.client.StreamingRecognize(ctx)
+// sessStream abstracts over the v1 and v2 Speech-to-Text streaming gRPC
+// clients so that `stream`/`trSess` do not need to know which API version
+// they are talking to.
+type sessStream interface {
+	send(p []byte) error
+	recv() ([]*tr.DiarizedStrTrRec, error)
+	closeSend() error
+}
+
+// openSessionV1 opens a Speech-to-Text v1 streaming session and wraps it as
+// a sessStream, so it can be used interchangeably with v2.
+func openSessionV1(ctx context.Context, client *speech.Client, req *tr.Req) (sessStream, error) {
+	stream, err := client.StreamingRecognize(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize text-to-speech stream: %w", err)
 	}
-	if err := sendConfig(stream, s.lang, s.speechContext, s.interim); err != nil {
+	if err := sendConfig(stream, req); err != nil {
+		return nil, err
+	}
+	return &v1SessStream{stream}, nil
+}
+
+// v1SessStream adapts speechpb.Speech_StreamingRecognizeClient to sessStream.
+type v1SessStream struct {
+	speechpb.Speech_StreamingRecognizeClient
+}
+
+func (s *v1SessStream) send(p []byte) error {
+	return s.Send(&speechpb.StreamingRecognizeRequest{
+		StreamingRequest: &speechpb.StreamingRecognizeRequest_AudioContent{
+			AudioContent: p,
+		},
+	})
+}
+
+func (s *v1SessStream) recv() ([]*tr.DiarizedStrTrRec, error) {
+	resp, err := s.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		// Just acknoledge that a non-fatal error occurred.
+		log.Printf("[INFO] session transmitter returned a status error: %v", resp.Error.Message)
+	}
+	return mapStreamSpeechResults(resp.Results), nil
+}
+
+func (s *v1SessStream) closeSend() error {
+	return s.CloseSend()
+}
+
+func (s *stream) openTrSession(ctx context.Context) (*trSess, error) {
+	sstream, err := s.newSession(ctx)
+	if err != nil {
 		return nil, err
 	}
 
 	sess := &trSess{
-		sstream: stream,
-		rx:      make(chan *sgenc.StrTrRec),
+		sstream: sstream,
+		rx:      make(chan *tr.DiarizedStrTrRec),
 	}
 	go sess.listenTr()
 
@@ -410,44 +481,43 @@ func (s *stream) openTrSession(ctx context.Context) (*trSess, error) {
 }
 
 type trSess struct {
-	sstream   speechpb.Speech_StreamingRecognizeClient
+	sstream   sessStream
 	bytesSent int
 	err       error
-	rx        chan *sgenc.StrTrRec
+	rx        chan *tr.DiarizedStrTrRec
 }
 
 func (s *trSess) sendAudio(p []byte) error {
-	if err := s.sstream.Send(&speechpb.StreamingRecognizeRequest{
-		StreamingRequest: &speechpb.StreamingRecognizeRequest_AudioContent{
-			AudioContent: p,
-		},
-	}); err != nil {
+	if err := s.sstream.send(p); err != nil {
 		return fmt.Errorf("unable to send audio buffer: %v", err)
 	}
 	s.bytesSent += len(p)
 	return nil
 }
 
-func mapStreamSpeechResults(results []*speechpb.StreamingRecognitionResult) []*sgenc.StrTrRec {
-	acc := []*sgenc.StrTrRec{}
+func mapStreamSpeechResults(results []*speechpb.StreamingRecognitionResult) []*tr.DiarizedStrTrRec {
+	acc := []*tr.DiarizedStrTrRec{}
 	for _, r := range results {
 		acc = append(acc, mapStreamSpeechResult(r)...)
 	}
 	return acc
 }
 
-func mapStreamSpeechResult(r *speechpb.StreamingRecognitionResult) []*sgenc.StrTrRec {
+func mapStreamSpeechResult(r *speechpb.StreamingRecognitionResult) []*tr.DiarizedStrTrRec {
 	alt := r.Alternatives[0]
-	recs := make([]*sgenc.StrTrRec, len(alt.Words))
+	recs := make([]*tr.DiarizedStrTrRec, len(alt.Words))
 	for i, w := range alt.Words {
-		recs[i] = &sgenc.StrTrRec{
-			TrRec: &sgenc.TrRec{
-				Start:   mapDuration(w.StartTime),
-				End:     mapDuration(w.EndTime),
-				TextRaw: w.Word,
+		recs[i] = &tr.DiarizedStrTrRec{
+			StrTrRec: &sgenc.StrTrRec{
+				TrRec: &sgenc.TrRec{
+					Start:   mapDuration(w.StartTime),
+					End:     mapDuration(w.EndTime),
+					TextRaw: w.Word,
+				},
+				IsFinal:    r.IsFinal,
+				Confidence: float64(alt.Confidence),
 			},
-			IsFinal:    r.IsFinal,
-			Confidence: float64(alt.Confidence),
+			SpeakerTag: int(w.SpeakerTag),
 		}
 	}
 	return recs
@@ -455,19 +525,13 @@ func mapStreamSpeechResult(r *speechpb.StreamingRecognitionResult) []*sgenc.StrT
 
 func (s *trSess) listenTr() {
 	for {
-		resp, err := s.sstream.Recv()
+		results, err := s.sstream.recv()
 		if err != nil {
 			log.Printf("[INFO] closing session transmitter: %v", err)
 			s.err = err
 			close(s.rx)
 			return
 		}
-		if resp.Error != nil {
-			// Just acknoledge that a non-fatal error occurred.
-			log.Printf("[INFO] session transmitter returned a status error: %v", resp.Error.Message)
-		}
-
-		results := mapStreamSpeechResults(resp.Results)
 		if len(results) == 0 {
 			log.Printf("[INFO] no transcript items received in response from google")
 			continue
@@ -481,22 +545,37 @@ func (s *trSess) listenTr() {
 
 func (c *Client) TranscribeStream(ctx context.Context, req *tr.Req, sessionTimeout time.Duration) (tr.TrStreamer, error) {
 	// Input needs to be transcoded to Linear16 first.
-	t := ffmpeg.New(ffmpeg.FormatL16(), ffmpeg.Input(req.Input))
+	t := ffmpeg.New(ffmpeg.FormatL16(), ffmpeg.Input(req.Input), ffmpeg.Channels(req.Channels))
 	if err := t.Start(); err != nil {
 		return nil, fmt.Errorf("unable to transcode input to linear 16: %w", err)
 	}
 
-	// Create a stream to Google Speech API and open it.
-	gsc, err := speech.NewClient(ctx, c.Opts...)
-	if err != nil {
-		t.Close()
-		return nil, fmt.Errorf("unable to open google speech client: %w", err)
+	// Build a session opener for whichever API version req selects. v1
+	// remains the default so existing callers are unaffected.
+	var newSession func(ctx context.Context) (sessStream, error)
+	if req.HasRecognizer() {
+		newSession = func(ctx context.Context) (sessStream, error) {
+			return c.openSessionV2(ctx, req)
+		}
+	} else {
+		gsc, err := speech.NewClient(ctx, c.Opts...)
+		if err != nil {
+			t.Close()
+			return nil, fmt.Errorf("unable to open google speech client: %w", err)
+		}
+		newSession = func(ctx context.Context) (sessStream, error) {
+			return openSessionV1(ctx, gsc, req)
+		}
 	}
+
 	stream := &stream{
-		client:         gsc,
+		newSession:     newSession,
 		lang:           req.Lang,
 		sessionTimeout: sessionTimeout,
 		interim:        req.Interim,
+		vadThreshold:   req.VADThreshold,
+		vadHangover:    req.VADHangover,
+		resetWindow:    req.ResetWindow,
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package google
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	speechv2 "cloud.google.com/go/speech/apiv2"
+	"git.keepinmind.info/subgensdk/sgenc"
+	"git.keepinmind.info/subgensdk/sgtr/ffmpeg"
+	"git.keepinmind.info/subgensdk/sgtr/tr"
+	"github.com/kim-company/pmux/pwrap"
+	"google.golang.org/api/option"
+	speechpb "google.golang.org/genproto/googleapis/cloud/speech/v2"
+)
+
+// speakerTagV2 converts the v2 API's string speaker label (e.g. "1", "2")
+// into the int tag used by DiarizedTrRec/DiarizedStrTrRec, so that callers
+// do not need to know v1 and v2 disagree on the wire representation. An
+// unparseable or absent label (diarization disabled) maps to 0.
+func speakerTagV2(label string) int {
+	n, _ := strconv.Atoi(label)
+	return n
+}
+
+// regionalEndpoint returns the Speech-to-Text v2 endpoint that has to be
+// used to reach a recognizer living in loc. Recognizers created outside of
+// "global" can only be reached through their regional endpoint.
+func regionalEndpoint(loc string) string {
+	return loc + "-speech.googleapis.com:443"
+}
+
+// newSpeechClientV2 returns a Speech-to-Text v2 client, routed to the
+// regional endpoint matching req's recognizer when it does not live in the
+// "global" location.
+func (c *Client) newSpeechClientV2(ctx context.Context, req *tr.Req) (*speechv2.Client, error) {
+	opts := c.Opts
+	if loc := req.Location(); loc != "global" {
+		opts = append(append([]option.ClientOption{}, opts...), option.WithEndpoint(regionalEndpoint(loc)))
+	}
+	return speechv2.NewClient(ctx, opts...)
+}
+
+func recognitionConfigV2(req *tr.Req) *speechpb.RecognitionConfig {
+	features := &speechpb.RecognitionFeatures{
+		EnableWordTimeOffsets:      true,
+		EnableAutomaticPunctuation: true,
+	}
+	if req.Channels > 1 {
+		features.MultiChannelMode = speechpb.RecognitionFeatures_SEPARATE_RECOGNITION_PER_CHANNEL
+	}
+	if req.HasDiarization() {
+		features.DiarizationConfig = &speechpb.SpeakerDiarizationConfig{
+			MinSpeakerCount: int32(req.Diarization.MinSpeakers),
+			MaxSpeakerCount: int32(req.Diarization.MaxSpeakers),
+		}
+	}
+
+	return &speechpb.RecognitionConfig{
+		DecodingConfig: &speechpb.RecognitionConfig_ExplicitDecodingConfig{
+			ExplicitDecodingConfig: &speechpb.ExplicitDecodingConfig{
+				Encoding:          speechpb.ExplicitDecodingConfig_LINEAR16,
+				SampleRateHertz:   16000,
+				AudioChannelCount: int32(req.Channels),
+			},
+		},
+		Model:         req.Model,
+		LanguageCodes: req.LanguageCodes(),
+		Features:      features,
+	}
+}
+
+func mapSpeechResultsV2(alts []*speechpb.SpeechRecognitionAlternative) []*tr.DiarizedTrRec {
+	if len(alts) == 0 {
+		return []*tr.DiarizedTrRec{}
+	}
+
+	// First alternative is the most probable one.
+	alt := alts[0]
+	acc := make([]*tr.DiarizedTrRec, 0, len(alt.Words))
+	for _, v := range alt.Words {
+		if v.Word == "" {
+			continue
+		}
+		acc = append(acc, &tr.DiarizedTrRec{
+			TrRec: &sgenc.TrRec{
+				Start:   v.StartTime.AsDuration(),
+				End:     v.EndTime.AsDuration(),
+				TextRaw: v.Word,
+			},
+			SpeakerTag: speakerTagV2(v.SpeakerLabel),
+		})
+	}
+	return acc
+}
+
+// transcribeFileV2 is the Speech-to-Text v2 counterpart of
+// (*Client).TranscribeFile, selected whenever req.HasRecognizer() is true.
+func (c *Client) transcribeFileV2(ctx context.Context, req *tr.Req, pf pwrap.WriteProgressUpdateFunc) ([]*tr.DiarizedTrRec, error) {
+	bkt, err := c.bucketFor(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer bkt.Delete(ctx, req.ID)
+
+	t := ffmpeg.New(ffmpeg.FormatL16(), ffmpeg.Input(req.Input), ffmpeg.Channels(req.Channels))
+	if err := t.Start(); err != nil {
+		return nil, fmt.Errorf("unable to transcode input to linear 16: %w", err)
+	}
+	defer t.Close()
+
+	pf("uploading", 1, 2, 0, 1)
+	uri, err := bkt.Upload(ctx, req.ID, t)
+	if err != nil {
+		return nil, fmt.Errorf("unable to upload input to google storage: %w", err)
+	}
+	pf("uploading", 1, 2, 1, 1)
+
+	gsc, err := c.newSpeechClientV2(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize google speech v2 client: %w", err)
+	}
+
+	pf("transcribing", 2, 2, 0, 1)
+	resp, err := gsc.Recognize(ctx, &speechpb.RecognizeRequest{
+		Recognizer: req.Recognizer,
+		Config:     recognitionConfigV2(req),
+		AudioSource: &speechpb.RecognizeRequest_Uri{
+			Uri: uri,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to complete transcript task: %w", err)
+	}
+	pf("transcribing", 2, 2, 1, 1)
+
+	records := []*tr.DiarizedTrRec{}
+	for _, v := range resp.Results {
+		records = append(records, mapSpeechResultsV2(v.Alternatives)...)
+	}
+	return records, nil
+}
+
+func mapStreamSpeechResultsV2(results []*speechpb.StreamingRecognitionResult) []*tr.DiarizedStrTrRec {
+	acc := []*tr.DiarizedStrTrRec{}
+	for _, r := range results {
+		if len(r.Alternatives) == 0 {
+			continue
+		}
+		alt := r.Alternatives[0]
+		for _, w := range alt.Words {
+			acc = append(acc, &tr.DiarizedStrTrRec{
+				StrTrRec: &sgenc.StrTrRec{
+					TrRec: &sgenc.TrRec{
+						Start:   w.StartTime.AsDuration(),
+						End:     w.EndTime.AsDuration(),
+						TextRaw: w.Word,
+					},
+					IsFinal:    r.IsFinal,
+					Confidence: float64(alt.Confidence),
+				},
+				SpeakerTag: speakerTagV2(w.SpeakerLabel),
+			})
+		}
+	}
+	return acc
+}
+
+func streamingConfigV2(req *tr.Req) *speechpb.StreamingRecognitionConfig {
+	return &speechpb.StreamingRecognitionConfig{
+		Config: recognitionConfigV2(req),
+		StreamingFeatures: &speechpb.StreamingRecognitionFeatures{
+			InterimResults:            req.Interim,
+			EnableVoiceActivityEvents: true,
+		},
+	}
+}
+
+func sendConfigV2(stream speechpb.Speech_StreamingRecognizeClient, req *tr.Req) error {
+	if err := stream.Send(&speechpb.StreamingRecognizeRequest{
+		Recognizer: req.Recognizer,
+		StreamingRequest: &speechpb.StreamingRecognizeRequest_StreamingConfig{
+			StreamingConfig: streamingConfigV2(req),
+		},
+	}); err != nil {
+		return fmt.Errorf("unable to send initial stream configuration message: %w", err)
+	}
+	return nil
+}
+
+// openSessionV2 opens a Speech-to-Text v2 streaming session against req's
+// recognizer and wraps it as a sessStream.
+func (c *Client) openSessionV2(ctx context.Context, req *tr.Req) (sessStream, error) {
+	gsc, err := c.newSpeechClientV2(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize google speech v2 client: %w", err)
+	}
+	stream, err := gsc.StreamingRecognize(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize text-to-speech v2 stream: %w", err)
+	}
+	if err := sendConfigV2(stream, req); err != nil {
+		return nil, err
+	}
+	return &v2SessStream{stream}, nil
+}
+
+// v2SessStream adapts speechpb.Speech_StreamingRecognizeClient (v2) to
+// sessStream.
+type v2SessStream struct {
+	speechpb.Speech_StreamingRecognizeClient
+}
+
+func (s *v2SessStream) send(p []byte) error {
+	return s.Send(&speechpb.StreamingRecognizeRequest{
+		StreamingRequest: &speechpb.StreamingRecognizeRequest_Audio{
+			Audio: p,
+		},
+	})
+}
+
+func (s *v2SessStream) recv() ([]*tr.DiarizedStrTrRec, error) {
+	resp, err := s.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return mapStreamSpeechResultsV2(resp.GetResults()), nil
+}
+
+func (s *v2SessStream) closeSend() error {
+	return s.CloseSend()
+}
@@ -8,15 +8,30 @@ package google
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
 
 	"cloud.google.com/go/storage"
+	"git.keepinmind.info/subgensdk/sgtr/blob"
+	gauth "golang.org/x/oauth2/google"
 )
 
+func init() {
+	blob.Register("gcs", func(ctx context.Context, cfg blob.Config) (blob.Bucket, error) {
+		return NewClient(ctx).NewBkt(ctx, cfg.Bucket)
+	})
+}
+
+// Bkt is a Google Cloud Storage bucket, implementing blob.Bucket.
 type Bkt struct {
 	name string
 	h    *storage.BucketHandle
 }
 
+var _ blob.Bucket = (*Bkt)(nil)
+
 func (c *Client) NewBkt(ctx context.Context, name string) (*Bkt, error) {
 	gsc, err := storage.NewClient(ctx, c.Opts...)
 	if err != nil {
@@ -26,18 +41,46 @@ func (c *Client) NewBkt(ctx context.Context, name string) (*Bkt, error) {
 	return &Bkt{h: gsc.Bucket(name), name: name}, nil
 }
 
-func (b *Bkt) Object(key string) *Obj {
-	return &Obj{ObjectHandle: b.h.Object(key), key: key, bkt: b.name}
+// Upload streams r to key, returning the object's "gs://" URI.
+func (b *Bkt) Upload(ctx context.Context, key string, r io.Reader) (string, error) {
+	w := b.h.Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return "", fmt.Errorf("unable to upload obj to gcs bkt: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("unable to finalize obj upload to gcs bkt: %w", err)
+	}
+	return "gs://" + b.name + "/" + key, nil
 }
 
-type Obj struct {
-	bkt string
-	key string
-	*storage.ObjectHandle
+func (b *Bkt) Delete(ctx context.Context, key string) error {
+	if err := b.h.Object(key).Delete(ctx); err != nil {
+		return fmt.Errorf("unable to delete obj from gcs bkt: %w", err)
+	}
+	return nil
 }
 
-func (o *Obj) URI() string { return "gs://" + o.bkt + "/" + o.key }
+// SignedURL returns a temporary GET URL for key, signed using the
+// service account pointed at by GOOGLE_APPLICATION_CREDENTIALS.
+func (b *Bkt) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	data, err := ioutil.ReadFile(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+	if err != nil {
+		return "", fmt.Errorf("unable to read service account credentials: %w", err)
+	}
+	conf, err := gauth.JWTConfigFromJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse service account credentials: %w", err)
+	}
 
-func (o *Obj) Trash() error {
-	return o.Delete(context.Background())
+	url, err := storage.SignedURL(b.name, key, &storage.SignedURLOptions{
+		GoogleAccessID: conf.Email,
+		PrivateKey:     conf.PrivateKey,
+		Method:         "GET",
+		Expires:        time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to sign url for %s: %w", key, err)
+	}
+	return url, nil
 }
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package google
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// vadFrameBitrate matches the single channel, 16kHz, Linear16 stream that
+// reaches the gate (see inBitrate). inBitrate itself is a var, not a
+// constant expression, so this can't be a const either.
+var vadFrameBitrate = inBitrate
+
+// vadGate is a simple energy-based voice activity detector with
+// hysteresis: rather than flipping to silence as soon as a single frame's
+// RMS dips below Threshold, it requires that condition to hold for at
+// least Hangover before reporting silence, so that brief dips in volume
+// within a sentence do not get mistaken for a pause between sentences.
+type vadGate struct {
+	// Threshold is the RMS level, normalized to [0, 1], below which a
+	// frame is considered silent.
+	Threshold float64
+	// Hangover is how long the RMS has to stay below Threshold before
+	// Classify starts reporting silence.
+	Hangover time.Duration
+
+	silenceFor time.Duration
+}
+
+// Classify updates the gate with Linear16 frame p (mono, 16 bit little
+// endian samples) and reports whether it should be considered speech.
+func (g *vadGate) Classify(p []byte) bool {
+	if rms16(p) >= g.Threshold {
+		g.silenceFor = 0
+		return true
+	}
+	g.silenceFor += frameDuration(len(p), vadFrameBitrate)
+	return g.silenceFor < g.Hangover
+}
+
+// rms16 computes the root-mean-square amplitude of p, a buffer of 16 bit
+// little endian PCM samples, normalized to [0, 1].
+func rms16(p []byte) float64 {
+	n := len(p) / 2
+	if n == 0 {
+		return 0
+	}
+
+	var sum float64
+	for i := 0; i < n; i++ {
+		s := int16(binary.LittleEndian.Uint16(p[i*2 : i*2+2]))
+		v := float64(s) / math.MaxInt16
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// frameDuration returns the audio duration represented by nbytes of
+// Linear16 audio sampled at the given bitrate (bits per second).
+func frameDuration(nbytes, bitrate int) time.Duration {
+	byteRate := float64(bitrate) / 8
+	if byteRate == 0 {
+		return 0
+	}
+	return time.Duration(float64(nbytes) / byteRate * float64(time.Second))
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package google
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// silentFrame returns n bytes of zeroed (silent) Linear16 audio.
+func silentFrame(n int) []byte {
+	return make([]byte, n)
+}
+
+// loudFrame returns n bytes of full-scale Linear16 audio, alternating
+// sample sign so consecutive samples don't cancel out under RMS.
+func loudFrame(n int) []byte {
+	p := make([]byte, n)
+	for i := 0; i+1 < n; i += 2 {
+		v := int16(math.MaxInt16)
+		if (i/2)%2 == 1 {
+			v = math.MinInt16 + 1
+		}
+		binary.LittleEndian.PutUint16(p[i:i+2], uint16(v))
+	}
+	return p
+}
+
+func TestRMS16(t *testing.T) {
+	if got := rms16(nil); got != 0 {
+		t.Fatalf("rms16(nil) = %v, want 0", got)
+	}
+	if got := rms16(silentFrame(320)); got != 0 {
+		t.Fatalf("rms16(silence) = %v, want 0", got)
+	}
+	if got := rms16(loudFrame(320)); math.Abs(got-1) > 1e-4 {
+		t.Fatalf("rms16(full scale) = %v, want ~1", got)
+	}
+}
+
+func TestFrameDuration(t *testing.T) {
+	// 3200 bytes at 16000*16 bits/s (16kHz, 16 bit, mono), i.e. 32000
+	// bytes/s, is exactly 100ms. Use the real vadFrameBitrate value so
+	// this tracks it if it ever changes.
+	got := frameDuration(vadFrameBitrate/8/10, vadFrameBitrate)
+	if got != 100*time.Millisecond {
+		t.Fatalf("frameDuration = %v, want 100ms", got)
+	}
+	if got := frameDuration(100, 0); got != 0 {
+		t.Fatalf("frameDuration with zero bitrate = %v, want 0", got)
+	}
+}
+
+func TestVADGateHysteresis(t *testing.T) {
+	g := &vadGate{Threshold: 0.5, Hangover: 250 * time.Millisecond}
+
+	// 100ms loud frame: above threshold, always speech, hangover resets.
+	loud := loudFrame(vadFrameBitrate / 8 / 10)
+	if !g.Classify(loud) {
+		t.Fatal("loud frame classified as silence")
+	}
+
+	// 100ms silent frame: below threshold, but hangover (250ms) hasn't
+	// elapsed yet, so it should still be reported as speech.
+	quiet := silentFrame(vadFrameBitrate / 8 / 10)
+	if !g.Classify(quiet) {
+		t.Fatal("first quiet frame should still be inside the hangover window")
+	}
+	if !g.Classify(quiet) {
+		t.Fatal("second quiet frame (200ms of silence) should still be inside the hangover window")
+	}
+	// A third 100ms quiet frame pushes accumulated silence past the
+	// 250ms hangover.
+	if g.Classify(quiet) {
+		t.Fatal("third quiet frame (300ms of silence) should trip the gate to silence")
+	}
+
+	// A loud frame resets the hangover counter.
+	if !g.Classify(loud) {
+		t.Fatal("loud frame after silence should be classified as speech again")
+	}
+	if !g.Classify(quiet) {
+		t.Fatal("quiet frame right after a loud one should still be inside a fresh hangover window")
+	}
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+// Package oss implements blob.Bucket on top of Aliyun Object Storage
+// Service, so that diroom can run entirely on Alibaba Cloud instead of
+// AWS or GCP.
+package oss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"git.keepinmind.info/subgensdk/sgtr/blob"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+func init() {
+	blob.Register("oss", func(ctx context.Context, cfg blob.Config) (blob.Bucket, error) {
+		c, err := NewClient(cfg.Endpoint, os.Getenv("OSS_ACCESS_KEY_ID"), os.Getenv("OSS_ACCESS_KEY_SECRET"))
+		if err != nil {
+			return nil, err
+		}
+		return c.NewBkt(cfg.Bucket)
+	})
+}
+
+// Client authenticates against an OSS endpoint. Initialize it with
+// NewClient.
+type Client struct {
+	c *oss.Client
+}
+
+// NewClient returns a new OSS client talking to endpoint (e.g.
+// "oss-eu-west-1.aliyuncs.com"), authenticated with the given access key
+// pair, following the same access-key/secret/endpoint/bucket model S3
+// uses.
+func NewClient(endpoint, accessKeyID, accessKeySecret string) (*Client, error) {
+	c, err := oss.New(endpoint, accessKeyID, accessKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create oss client: %w", err)
+	}
+	return &Client{c: c}, nil
+}
+
+// Bkt is an OSS bucket, implementing blob.Bucket.
+type Bkt struct {
+	b *oss.Bucket
+}
+
+var _ blob.Bucket = (*Bkt)(nil)
+
+func (c *Client) NewBkt(name string) (*Bkt, error) {
+	b, err := c.c.Bucket(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open oss bkt %s: %w", name, err)
+	}
+	return &Bkt{b: b}, nil
+}
+
+func (b *Bkt) Upload(ctx context.Context, key string, r io.Reader) (string, error) {
+	if err := b.b.PutObject(key, r); err != nil {
+		return "", fmt.Errorf("unable to upload obj to oss bkt: %w", err)
+	}
+	return fmt.Sprintf("oss://%s/%s", b.b.BucketName, key), nil
+}
+
+func (b *Bkt) Delete(ctx context.Context, key string) error {
+	if err := b.b.DeleteObject(key); err != nil {
+		return fmt.Errorf("unable to delete obj from oss bkt: %w", err)
+	}
+	return nil
+}
+
+func (b *Bkt) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	url, err := b.b.SignURL(key, oss.HTTPGet, int64(ttl.Seconds()))
+	if err != nil {
+		return "", fmt.Errorf("unable to sign url for %s: %w", key, err)
+	}
+	return url, nil
+}
@@ -0,0 +1,7 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package rpc
+
+//go:generate make -C .. proto
@@ -0,0 +1,350 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+// Package rpc implements rpcpb.TranscriptionServiceServer on top of
+// tr.Transcriber, so that sgtrd can expose the same SubmitFile/
+// WatchProgress/StreamAudio/Cancel surface described in
+// transcription.proto. rpcpb itself is generated from that file with
+// `protoc -I. --go_out=plugins=grpc:. rpc/transcription.proto` (see the
+// proto Makefile target); run it before building this package.
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"git.keepinmind.info/subgensdk/sgtr/blob"
+	"git.keepinmind.info/subgensdk/sgtr/rpc/rpcpb"
+	"git.keepinmind.info/subgensdk/sgtr/tr"
+	"github.com/google/uuid"
+	"github.com/kim-company/pmux/pwrap"
+)
+
+// job tracks the bookkeeping a single in-flight transcription needs
+// across RPCs: SubmitFile/StreamAudio own it, WatchProgress only reads
+// from it, and Cancel tears it down.
+type job struct {
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	subs []chan *rpcpb.ProgressUpdate
+}
+
+func newJob(cancel context.CancelFunc) *job {
+	return &job{cancel: cancel}
+}
+
+// publish fans out an update to every WatchProgress call currently
+// attached to this job; it never blocks on a slow subscriber.
+func (j *job) publish(u *rpcpb.ProgressUpdate) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, ch := range j.subs {
+		select {
+		case ch <- u:
+		default:
+		}
+	}
+}
+
+func (j *job) subscribe() chan *rpcpb.ProgressUpdate {
+	ch := make(chan *rpcpb.ProgressUpdate, 16)
+	j.mu.Lock()
+	j.subs = append(j.subs, ch)
+	j.mu.Unlock()
+	return ch
+}
+
+func (j *job) unsubscribe(ch chan *rpcpb.ProgressUpdate) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i, s := range j.subs {
+		if s == ch {
+			j.subs = append(j.subs[:i], j.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Service implements rpcpb.TranscriptionServiceServer against eng, the
+// same Transcriber backend sgtr's CLI drives.
+type Service struct {
+	rpcpb.UnimplementedTranscriptionServiceServer
+
+	eng    tr.Transcriber
+	region string
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// NewService returns a Service that submits jobs to eng. region is
+// stored on the Service; none of its RPCs read it.
+func NewService(eng tr.Transcriber, region string) *Service {
+	return &Service{eng: eng, region: region, jobs: map[string]*job{}}
+}
+
+func reqFromPB(p *rpcpb.Request) *tr.Req {
+	opts := []func(*tr.Req){
+		tr.Input(p.Input),
+		tr.Language(p.Lang),
+		tr.Bucket(p.Bkt),
+		tr.ID(p.Id),
+		tr.SpeechContext(p.SpeechContext),
+		tr.Interim(p.Interim),
+		tr.Recognizer(p.Recognizer),
+		tr.Model(p.Model),
+	}
+	if p.Channels > 0 {
+		opts = append(opts, tr.Channels(int(p.Channels)))
+	}
+	if len(p.Langs) > 0 {
+		opts = append(opts, tr.Languages(p.Langs...))
+	}
+	if p.VadThreshold > 0 {
+		opts = append(opts, tr.VAD(p.VadThreshold, time.Duration(p.VadHangoverMs)*time.Millisecond))
+	}
+	if p.ResetWindowMs > 0 {
+		opts = append(opts, tr.ResetWindow(time.Duration(p.ResetWindowMs)*time.Millisecond))
+	}
+	if d := p.Diarization; d != nil && (d.MinSpeakers > 0 || d.MaxSpeakers > 0) {
+		opts = append(opts, tr.Diarize(int(d.MinSpeakers), int(d.MaxSpeakers)))
+	}
+	if s := p.Storage; s != nil && s.Backend != "" {
+		opts = append(opts, tr.Storage(blob.Config{
+			Backend:  s.Backend,
+			Bucket:   s.Bucket,
+			Region:   s.Region,
+			Endpoint: s.Endpoint,
+		}))
+	}
+	return tr.NewReq(opts...)
+}
+
+// recordToPB drops r.SpeakerTag: TranscriptRecord mirrors sgenc.StrTrRec
+// and has no field for it (see tr.DiarizedTrRec).
+func recordToPB(r *tr.DiarizedTrRec) *rpcpb.TranscriptRecord {
+	return &rpcpb.TranscriptRecord{
+		StartMs: r.Start.Milliseconds(),
+		EndMs:   r.End.Milliseconds(),
+		TextRaw: r.TextRaw,
+		IsFinal: true,
+	}
+}
+
+func strRecordToPB(r *tr.DiarizedStrTrRec) *rpcpb.TranscriptRecord {
+	return &rpcpb.TranscriptRecord{
+		StartMs: r.Start.Milliseconds(),
+		EndMs:   r.End.Milliseconds(),
+		TextRaw: r.TextRaw,
+		IsFinal: r.IsFinal,
+	}
+}
+
+// SubmitFile runs req through eng.TranscribeFile, publishing its progress
+// to every WatchProgress call registered against the returned job id
+// before SubmitFile itself returns.
+func (s *Service) SubmitFile(ctx context.Context, req *rpcpb.SubmitFileRequest) (*rpcpb.SubmitFileResponse, error) {
+	jobID := uuid.New().String()
+	ctx, cancel := context.WithCancel(ctx)
+
+	j := newJob(cancel)
+	s.mu.Lock()
+	s.jobs[jobID] = j
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.jobs, jobID)
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	pf := pwrap.WriteProgressUpdateFunc(func(desc string, stage, stages, part, total int) error {
+		j.publish(&rpcpb.ProgressUpdate{
+			Description: desc,
+			Stage:       int32(stage),
+			Stages:      int32(stages),
+			Part:        int32(part),
+			Total:       int32(total),
+		})
+		return nil
+	})
+
+	records, err := s.eng.TranscribeFile(ctx, reqFromPB(req.Request), pf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to transcribe file: %w", err)
+	}
+
+	pbrecs := make([]*rpcpb.TranscriptRecord, len(records))
+	for i, r := range records {
+		pbrecs[i] = recordToPB(r)
+	}
+	return &rpcpb.SubmitFileResponse{JobId: jobID, Records: pbrecs}, nil
+}
+
+// WatchProgress streams req.JobId's progress updates until the client
+// disconnects. It does not return on its own when the job ends: nothing
+// closes the subscription channel.
+func (s *Service) WatchProgress(req *rpcpb.WatchProgressRequest, stream rpcpb.TranscriptionService_WatchProgressServer) error {
+	s.mu.Lock()
+	j, ok := s.jobs[req.JobId]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown job %q", req.JobId)
+	}
+
+	ch := j.subscribe()
+	defer j.unsubscribe(ch)
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case u, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(u); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// clientJobID validates a client-supplied AudioFrame.JobId, generating one
+// if the client left it empty. It never returns raw client input: id is
+// joined into a filesystem path and used as an s.jobs map key, so an
+// unvalidated value would let a client escape os.TempDir() (e.g. a job_id
+// of "../../../tmp/evil") or collide with another client's in-flight job.
+func clientJobID(id string) (string, error) {
+	if id == "" {
+		return uuid.New().String(), nil
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return "", fmt.Errorf("job_id must be a UUID: %w", err)
+	}
+	return id, nil
+}
+
+// StreamAudio opens a tr.Transcriber streaming session, writing the PCM
+// frames it receives into the backend and relaying every StrTrRec it
+// produces back to the client. The first AudioFrame on the stream must
+// carry JobId and Request; subsequent frames only need Pcm. JobId, if
+// given, must be a UUID.
+func (s *Service) StreamAudio(stream rpcpb.TranscriptionService_StreamAudioServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("unable to read first audio frame: %w", err)
+	}
+	if first.Request == nil {
+		return fmt.Errorf("first audio frame must carry a request")
+	}
+
+	jobID, err := clientJobID(first.JobId)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	j := newJob(cancel)
+	s.mu.Lock()
+	if _, taken := s.jobs[jobID]; taken {
+		s.mu.Unlock()
+		return fmt.Errorf("job %q already in use", jobID)
+	}
+	s.jobs[jobID] = j
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.jobs, jobID)
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	// Backends read their audio off req.Input, a path (or "-" for the
+	// process's own stdin); there is no io.Reader injection point. A
+	// named pipe gives us a path ffmpeg can -i just as it would a file,
+	// while still letting this goroutine feed it arbitrary bytes as they
+	// arrive over the gRPC stream.
+	fifo := filepath.Join(os.TempDir(), "sgtrd-"+jobID+".pcm")
+	if err := syscall.Mkfifo(fifo, 0600); err != nil {
+		return fmt.Errorf("unable to create audio fifo: %w", err)
+	}
+	defer os.Remove(fifo)
+
+	req := reqFromPB(first.Request)
+	req.Input = fifo
+
+	writeErrc := make(chan error, 1)
+	go func() {
+		w, err := os.OpenFile(fifo, os.O_WRONLY, os.ModeNamedPipe)
+		if err != nil {
+			writeErrc <- fmt.Errorf("unable to open audio fifo for writing: %w", err)
+			return
+		}
+		defer w.Close()
+
+		if _, err := w.Write(first.Pcm); err != nil {
+			writeErrc <- err
+			return
+		}
+		for {
+			frame, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF {
+					writeErrc <- err
+				}
+				return
+			}
+			if _, err := w.Write(frame.Pcm); err != nil {
+				writeErrc <- err
+				return
+			}
+		}
+	}()
+
+	sessionTimeout := 15 * time.Second
+	if req.ResetWindow > 0 {
+		sessionTimeout = req.ResetWindow
+	}
+	trs, err := s.eng.TranscribeStream(ctx, req, sessionTimeout)
+	if err != nil {
+		return fmt.Errorf("unable to start stream: %w", err)
+	}
+
+	for rec := range trs.Rx() {
+		if err := stream.Send(strRecordToPB(rec)); err != nil {
+			return err
+		}
+	}
+	if err := trs.Err(); err != nil {
+		return err
+	}
+	select {
+	case err := <-writeErrc:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Cancel stops the job registered under req.JobId, the RPC equivalent of
+// sending "cancel" over a pwrap.UnixCommBridge socket.
+func (s *Service) Cancel(ctx context.Context, req *rpcpb.CancelRequest) (*rpcpb.CancelResponse, error) {
+	s.mu.Lock()
+	j, ok := s.jobs[req.JobId]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown job %q", req.JobId)
+	}
+	j.cancel()
+	return &rpcpb.CancelResponse{}, nil
+}
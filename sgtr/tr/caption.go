@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package tr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// Cue is a caption cue: a span of time together with the text spoken
+// during it.
+type Cue struct {
+	Index int
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// CaptionFormat selects the subtitle format CaptionWriter renders cues in.
+type CaptionFormat int
+
+const (
+	WebVTT CaptionFormat = iota
+	SRT
+)
+
+// cueAccumulator coalesces the final records of a streaming transcription
+// into Cue values, flushing whenever the configured max duration or
+// character count is hit. It underlies both CaptionWriter, which renders
+// cues as WebVTT/SRT text, and LiveCaptioner, which delivers them over a
+// channel. Interim (non-final) records are ignored, since backends are
+// free to rewrite them before they become final; record timestamps are
+// expected to already be wall-clock aligned (streaming backends apply
+// their own timeshift offset before a record reaches Rx).
+type cueAccumulator struct {
+	maxDur   time.Duration
+	maxChars int
+
+	seq      int
+	cueStart time.Duration
+	cueEnd   time.Duration
+	words    []string
+}
+
+func (a *cueAccumulator) add(rec *DiarizedStrTrRec) (Cue, bool) {
+	if !rec.IsFinal {
+		return Cue{}, false
+	}
+	if len(a.words) == 0 {
+		a.cueStart = rec.Start
+	}
+	a.words = append(a.words, rec.TextRaw)
+	a.cueEnd = rec.End
+
+	text := strings.Join(a.words, " ")
+	if a.cueEnd-a.cueStart < a.maxDur && len(text) < a.maxChars {
+		return Cue{}, false
+	}
+	return a.drain(text), true
+}
+
+func (a *cueAccumulator) flush() (Cue, bool) {
+	if len(a.words) == 0 {
+		return Cue{}, false
+	}
+	return a.drain(strings.Join(a.words, " ")), true
+}
+
+func (a *cueAccumulator) drain(text string) Cue {
+	a.seq++
+	cue := Cue{Index: a.seq, Start: a.cueStart, End: a.cueEnd, Text: text}
+	a.words = a.words[:0]
+	return cue
+}
+
+// CaptionWriter coalesces the final records of a streaming transcription
+// into cues and renders them, as soon as each one is ready, to an
+// underlying io.Writer in the configured CaptionFormat.
+type CaptionWriter struct {
+	w      io.Writer
+	format CaptionFormat
+	acc    cueAccumulator
+
+	wroteHeader bool
+}
+
+// NewCaptionWriter returns a CaptionWriter rendering to w, configured by
+// opts. It defaults to WebVTT, 4 second / 64 character cues.
+func NewCaptionWriter(w io.Writer, opts ...func(*CaptionWriter)) *CaptionWriter {
+	cw := &CaptionWriter{
+		w:      w,
+		format: WebVTT,
+		acc: cueAccumulator{
+			maxDur:   4 * time.Second,
+			maxChars: 64,
+		},
+	}
+	for _, f := range opts {
+		f(cw)
+	}
+	return cw
+}
+
+// Format sets the subtitle format a CaptionWriter renders cues in.
+func Format(f CaptionFormat) func(*CaptionWriter) {
+	return func(cw *CaptionWriter) {
+		cw.format = f
+	}
+}
+
+// MaxCueDuration bounds how long a single cue is allowed to span before
+// CaptionWriter flushes it.
+func MaxCueDuration(d time.Duration) func(*CaptionWriter) {
+	return func(cw *CaptionWriter) {
+		cw.acc.maxDur = d
+	}
+}
+
+// MaxCueChars bounds how many characters a single cue is allowed to hold
+// before CaptionWriter flushes it.
+func MaxCueChars(n int) func(*CaptionWriter) {
+	return func(cw *CaptionWriter) {
+		cw.acc.maxChars = n
+	}
+}
+
+// Write adds rec to the cue currently being built, rendering and writing
+// it out once it hits the configured max duration or character count.
+func (cw *CaptionWriter) Write(rec *DiarizedStrTrRec) error {
+	if cue, ok := cw.acc.add(rec); ok {
+		return cw.writeCue(cue)
+	}
+	return nil
+}
+
+// Flush renders whatever has been buffered as a final, possibly short,
+// cue. Call it once the source stream is done to avoid losing a trailing
+// cue that never hit the max duration/character count.
+func (cw *CaptionWriter) Flush() error {
+	if cue, ok := cw.acc.flush(); ok {
+		return cw.writeCue(cue)
+	}
+	return nil
+}
+
+func (cw *CaptionWriter) writeCue(cue Cue) error {
+	if cw.format == WebVTT && !cw.wroteHeader {
+		if _, err := fmt.Fprint(cw.w, "WEBVTT\n\n"); err != nil {
+			return fmt.Errorf("unable to write webvtt header: %w", err)
+		}
+	}
+	cw.wroteHeader = true
+
+	var err error
+	switch cw.format {
+	case SRT:
+		_, err = fmt.Fprintf(cw.w, "%d\n%s --> %s\n%s\n\n",
+			cue.Index, formatTimestamp(cue.Start, ","), formatTimestamp(cue.End, ","), cue.Text)
+	default:
+		_, err = fmt.Fprintf(cw.w, "%s --> %s\n%s\n\n",
+			formatTimestamp(cue.Start, "."), formatTimestamp(cue.End, "."), cue.Text)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write cue: %w", err)
+	}
+	return nil
+}
+
+func formatTimestamp(d time.Duration, msSep string) string {
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	ms := d / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", h, m, s, msSep, ms)
+}
+
+// LiveCaptioner opens a streaming transcription session against eng and
+// coalesces its final records into cues as they arrive, replacing the
+// "open stream, range Rx, format yourself" boilerplate with a single
+// call. Audio is read from req.Input exactly as with TranscribeStream
+// (e.g. "-" to consume stdin, the usual setup for microphones and live
+// streams); the returned TrStreamer is still useful to check Err() once
+// the cue channel closes.
+func LiveCaptioner(ctx context.Context, eng Transcriber, req *Req, sessionTimeout time.Duration) (<-chan Cue, TrStreamer, error) {
+	stream, err := eng.TranscribeStream(ctx, req, sessionTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cues := make(chan Cue)
+	go func() {
+		defer close(cues)
+
+		var acc cueAccumulator
+		acc.maxDur = 4 * time.Second
+		acc.maxChars = 64
+		for rec := range stream.Rx() {
+			if cue, ok := acc.add(rec); ok {
+				cues <- cue
+			}
+		}
+		if cue, ok := acc.flush(); ok {
+			cues <- cue
+		}
+		if err := stream.Err(); err != nil {
+			log.Printf("[ERROR] live captioner stream exited with error: %v", err)
+		}
+	}()
+
+	return cues, stream, nil
+}
@@ -0,0 +1,24 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package tr
+
+import "git.keepinmind.info/subgensdk/sgenc"
+
+// DiarizedTrRec pairs a transcript record with the speaker a backend
+// attributed it to (see Req.Diarization). sgenc.TrRec has no room for this
+// field (it lives in the external sgenc module, which this repo does not
+// control), so it is kept here as a sibling type. SpeakerTag is 0 when the
+// request did not enable diarization or the backend does not support it.
+type DiarizedTrRec struct {
+	*sgenc.TrRec
+	SpeakerTag int
+}
+
+// DiarizedStrTrRec is DiarizedTrRec's streaming counterpart, pairing a
+// sgenc.StrTrRec with the speaker it was attributed to.
+type DiarizedStrTrRec struct {
+	*sgenc.StrTrRec
+	SpeakerTag int
+}
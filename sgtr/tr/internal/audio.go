@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+// Package internal collects helpers shared by the Transcriber backends
+// living under tr/: transcoding the input to the Linear16 format most
+// speech-to-text providers expect, converting bytes sent into elapsed
+// audio duration, and tracking when a backend session is approaching a
+// provider-imposed time ceiling and should be reset.
+package internal
+
+import (
+	"time"
+
+	"git.keepinmind.info/subgensdk/sgtr/ffmpeg"
+)
+
+// Linear16 returns a Transcoder producing single channel, 16kHz, Linear16
+// encoded audio starting from input, ready to be sent to a speech-to-text
+// backend.
+func Linear16(input string) *ffmpeg.Transcoder {
+	return ffmpeg.New(ffmpeg.FormatL16(), ffmpeg.Input(input))
+}
+
+// TimeshiftOffset returns the audio duration represented by bytesSent,
+// given the input's bitRate expressed in bits per second. It is used to
+// shift matched content timestamps back onto the original timeline after a
+// backend session has been reset.
+func TimeshiftOffset(bytesSent, bitRate int) time.Duration {
+	byteRate := float64(bitRate) / 8
+	if byteRate == 0 {
+		return 0
+	}
+	secs := float64(bytesSent) / byteRate
+	return time.Duration(secs * float64(time.Second))
+}
+
+// ResetTimer tracks, from the amount of audio bytes sent to a backend
+// session, whether that session is about to hit the provider's
+// per-session time ceiling and should be reset before it is cut off
+// mid-stream.
+type ResetTimer struct {
+	// BitRate is the input's bitrate, expressed in bits per second.
+	BitRate int
+	// Ceiling is the maximum session duration the backend allows.
+	Ceiling time.Duration
+
+	bytesSent int
+}
+
+// Add accounts for n additional bytes having been sent in the current
+// session.
+func (r *ResetTimer) Add(n int) {
+	r.bytesSent += n
+}
+
+// Elapsed returns the audio duration sent so far in the current session.
+func (r *ResetTimer) Elapsed() time.Duration {
+	return TimeshiftOffset(r.bytesSent, r.BitRate)
+}
+
+// ShouldReset reports whether Elapsed has reached Ceiling, meaning the
+// session should be closed and reopened.
+func (r *ResetTimer) ShouldReset() bool {
+	return r.Elapsed() >= r.Ceiling
+}
+
+// Reset zeroes the tracked byte count, to be called every time a new
+// session is opened.
+func (r *ResetTimer) Reset() {
+	r.bytesSent = 0
+}
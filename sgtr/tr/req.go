@@ -8,12 +8,14 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
-	"git.keepinmind.info/subgensdk/sgenc"
+	"git.keepinmind.info/subgensdk/sgtr/blob"
 )
 
 type TrStreamer interface {
-	Rx() <-chan *sgenc.StrTrRec
+	Rx() <-chan *DiarizedStrTrRec
 	Err() error
 }
 
@@ -24,6 +26,56 @@ type Req struct {
 	ID            string
 	SpeechContext string
 	Interim       bool
+
+	// Recognizer, when set, selects Speech-to-Text v2 and holds the
+	// full recognizer resource name, e.g.
+	// "projects/{project}/locations/{location}/recognizers/{recognizer}".
+	// Backends that do not support v2 can ignore it; the google backend
+	// uses it to decide whether to talk to v1 or v2.
+	Recognizer string
+	// Model selects the recognition model to use with the recognizer,
+	// e.g. "long", "chirp" or "latest_long". Only meaningful together
+	// with Recognizer.
+	Model string
+	// Langs holds the list of BCP-47 language codes the recognizer
+	// should expect, in order of preference. When empty, Lang is used
+	// instead.
+	Langs []string
+
+	// VADThreshold is the RMS level, normalized to [0, 1], below which
+	// audio is considered silent by streaming backends that support
+	// voice-activity-driven session resets.
+	VADThreshold float64
+	// VADHangover is how long audio has to stay below VADThreshold
+	// before it is treated as a confirmed silence run.
+	VADHangover time.Duration
+	// ResetWindow is how long before a streaming session's hard timeout
+	// a backend should start looking for a confirmed silence run to
+	// reset the session on, instead of waiting for the timeout and
+	// risking cutting a sentence mid-word.
+	ResetWindow time.Duration
+
+	// Channels is the number of audio channels in Input. When greater
+	// than 1, backends that support it recognize each channel
+	// separately instead of downmixing to mono. Defaults to 1.
+	Channels int
+	// Diarization, when non-zero, asks the backend to additionally
+	// attribute each recognized word to a speaker.
+	Diarization Diarization
+
+	// Storage, when its Backend field is set, tells backends to stage
+	// their input (and vocabulary, where applicable) through the
+	// blob.Bucket it describes instead of their own cloud's native
+	// bucket, so that e.g. the aws engine can run against an Aliyun OSS
+	// bucket instead of S3.
+	Storage blob.Config
+}
+
+// Diarization bounds the number of distinct speakers a backend should
+// look for when diarization is enabled on a Req.
+type Diarization struct {
+	MinSpeakers int
+	MaxSpeakers int
 }
 
 func Interim(ok bool) func(*Req) {
@@ -44,6 +96,14 @@ func Bucket(bkt string) func(*Req) {
 	}
 }
 
+// Storage sets Req.Storage, overriding the backend's own bucket with
+// cfg.
+func Storage(cfg blob.Config) func(*Req) {
+	return func(t *Req) {
+		t.Storage = cfg
+	}
+}
+
 func ID(id string) func(*Req) {
 	return func(t *Req) {
 		t.ID = id
@@ -62,11 +122,65 @@ func SpeechContext(s string) func(*Req) {
 	}
 }
 
+func Recognizer(name string) func(*Req) {
+	return func(t *Req) {
+		t.Recognizer = name
+	}
+}
+
+func Model(name string) func(*Req) {
+	return func(t *Req) {
+		t.Model = name
+	}
+}
+
+func Languages(codes ...string) func(*Req) {
+	return func(t *Req) {
+		t.Langs = codes
+	}
+}
+
+// VAD sets the RMS threshold and hangover used to tell speech from
+// silence in a streaming session; see Req.VADThreshold and
+// Req.VADHangover.
+func VAD(threshold float64, hangover time.Duration) func(*Req) {
+	return func(t *Req) {
+		t.VADThreshold = threshold
+		t.VADHangover = hangover
+	}
+}
+
+// ResetWindow sets Req.ResetWindow.
+func ResetWindow(d time.Duration) func(*Req) {
+	return func(t *Req) {
+		t.ResetWindow = d
+	}
+}
+
+// Channels sets Req.Channels.
+func Channels(n int) func(*Req) {
+	return func(t *Req) {
+		t.Channels = n
+	}
+}
+
+// Diarize enables diarization, bounding the number of speakers the
+// backend should look for between min and max.
+func Diarize(min, max int) func(*Req) {
+	return func(t *Req) {
+		t.Diarization = Diarization{MinSpeakers: min, MaxSpeakers: max}
+	}
+}
+
 func NewReq(opts ...func(t *Req)) *Req {
 	t := &Req{
 		// Set defaults here
-		Lang:  "en-US",
-		Input: "-",
+		Lang:         "en-US",
+		Input:        "-",
+		VADThreshold: 0.01,
+		VADHangover:  time.Second,
+		ResetWindow:  15 * time.Second,
+		Channels:     1,
 	}
 	// User defined conf.
 	for _, f := range opts {
@@ -79,6 +193,39 @@ func (r *Req) HasSpeechContext() bool {
 	return r.SpeechContext != ""
 }
 
+// HasRecognizer reports whether this request targets Speech-to-Text v2
+// through a recognizer resource name.
+func (r *Req) HasRecognizer() bool {
+	return r.Recognizer != ""
+}
+
+// Location extracts the "locations/{location}" segment out of Recognizer,
+// returning "global" when it cannot be determined.
+func (r *Req) Location() string {
+	parts := strings.Split(r.Recognizer, "/")
+	for i, v := range parts {
+		if v == "locations" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return "global"
+}
+
+// LanguageCodes returns Langs when set, falling back to a single-element
+// slice built from Lang otherwise.
+func (r *Req) LanguageCodes() []string {
+	if len(r.Langs) > 0 {
+		return r.Langs
+	}
+	return []string{r.Lang}
+}
+
+// HasDiarization reports whether this request asked the backend to
+// attribute words to speakers.
+func (r *Req) HasDiarization() bool {
+	return r.Diarization.MinSpeakers > 0 || r.Diarization.MaxSpeakers > 0
+}
+
 func (r *Req) ReadSpeechContext() ([]string, error) {
 	if !r.HasSpeechContext() {
 		return []string{}, nil
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package tr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Segment is a span of a streaming transcription accumulated by
+// AudioSegmentStream: a run of records, interim or final, that belong to
+// the same fixed-duration window. Final is set once the backend has
+// endpointed the last record appended to it, so that downstream consumers
+// (e.g. a committer writing segments to storage) know it is safe to treat
+// the segment as closed.
+type Segment struct {
+	ID      string
+	Start   time.Duration
+	End     time.Duration
+	Records []*DiarizedStrTrRec
+	Final   bool
+}
+
+// SegmentProgress reports a Segment alongside the usual pwrap-style
+// stage/progress counters, so a caller can surface streaming progress the
+// same way TranscribeFile already does through
+// pwrap.WriteProgressUpdateFunc. Stages is 0 when the total segment count
+// isn't known ahead of time, which is the case for every backend today
+// since the session length is open ended.
+type SegmentProgress struct {
+	Segment *Segment
+	Stage   int
+	Stages  int
+}
+
+// AudioSegmentStream groups the records off a TrStreamer into fixed
+// duration segments. It is a thin layer above TrStreamer, not a
+// replacement for it: backends keep reporting records exactly as they do
+// today (interim ones with IsFinal false, endpointed ones with IsFinal
+// true), and AudioSegmentStream is the piece that decides when enough of
+// them have accumulated to close out a Segment, using the backend's own
+// endpointing signal to avoid ever cutting a segment mid-word.
+type AudioSegmentStream struct {
+	stream   TrStreamer
+	segDur   time.Duration
+	cur      *Segment
+	seq      int
+	finished bool
+}
+
+// NewAudioSegmentStream wraps stream, closing out a Segment every time
+// segDur worth of endpointed audio has accumulated. segDur is expected to
+// be driven by the same session interval callers already pass to
+// Transcriber.TranscribeStream.
+func NewAudioSegmentStream(stream TrStreamer, segDur time.Duration) *AudioSegmentStream {
+	return &AudioSegmentStream{stream: stream, segDur: segDur}
+}
+
+// Next blocks until the current Segment is ready to be committed, either
+// because segDur worth of endpointed audio has accumulated or because the
+// underlying stream has ended. In the latter case the final (possibly
+// short) Segment is returned with Final set in the same call as io.EOF (or
+// the stream's own error, see eofOrErr), and every later call returns
+// io.EOF.
+func (a *AudioSegmentStream) Next(ctx context.Context) (SegmentProgress, error) {
+	if a.finished {
+		return SegmentProgress{}, io.EOF
+	}
+	if a.cur == nil {
+		a.seq++
+		a.cur = &Segment{ID: fmt.Sprintf("seg-%d", a.seq)}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return SegmentProgress{}, ctx.Err()
+		case rec, ok := <-a.stream.Rx():
+			if !ok {
+				a.finished = true
+				seg := a.cur
+				a.cur = nil
+				seg.Final = true
+				if len(seg.Records) == 0 {
+					return SegmentProgress{}, a.eofOrErr()
+				}
+				return a.progress(seg), a.eofOrErr()
+			}
+
+			if len(a.cur.Records) == 0 {
+				a.cur.Start = rec.Start
+			}
+			a.cur.End = rec.End
+			a.cur.Records = append(a.cur.Records, rec)
+
+			if rec.IsFinal && a.cur.End-a.cur.Start >= a.segDur {
+				seg := a.cur
+				seg.Final = true
+				a.cur = nil
+				return a.progress(seg), nil
+			}
+		}
+	}
+}
+
+// eofOrErr reports the underlying stream's terminal error, if any, or
+// io.EOF otherwise; it is only meaningful once the stream's Rx channel
+// has closed.
+func (a *AudioSegmentStream) eofOrErr() error {
+	if err := a.stream.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (a *AudioSegmentStream) progress(seg *Segment) SegmentProgress {
+	return SegmentProgress{Segment: seg, Stage: a.seq}
+}
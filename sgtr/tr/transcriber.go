@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2020 KIM KeepInMind GmbH
+//
+// SPDX-License-Identifier: MIT
+
+package tr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kim-company/pmux/pwrap"
+)
+
+// Transcriber is implemented by every speech-to-text backend diroom can
+// drive: it can transcribe a whole file at once, or turn a live audio
+// stream into transcript records as they become available.
+type Transcriber interface {
+	TranscribeFile(ctx context.Context, req *Req, pf pwrap.WriteProgressUpdateFunc) ([]*DiarizedTrRec, error)
+	TranscribeStream(ctx context.Context, req *Req, sessionTimeout time.Duration) (TrStreamer, error)
+}
+
+// Factory builds a Transcriber, given a region (used by cloud backends that
+// are region-aware; ignored by the ones that are not).
+type Factory func(ctx context.Context, region string) (Transcriber, error)
+
+var backends = map[string]Factory{}
+
+// Register makes a Transcriber backend available under name. Backend
+// packages are expected to call it from an init function, so that
+// importing them for their side effect is enough to make them selectable
+// through New.
+func Register(name string, f Factory) {
+	backends[name] = f
+}
+
+// New returns the Transcriber backend registered under name, or an error if
+// none matches.
+func New(ctx context.Context, name, region string) (Transcriber, error) {
+	f, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported transcription engine %s", name)
+	}
+	return f(ctx, region)
+}